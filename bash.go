@@ -0,0 +1,311 @@
+package dotenv
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// bashOperation is a bash string operation on a variable, like ${VAR#pattern}
+type bashOperation struct {
+	name string
+	// operator is "length" for ${#VAR}, "substring" for ${VAR:offset:length}, or the operator following
+	// the variable name, like "#" or "//"
+	operator string
+	// arg is the text following the operator
+	arg string
+	// argStart is the offset of arg in the ${...} content
+	argStart int
+}
+
+// bashOperators are the operators following the variable name in bash string operations, longest first
+var bashOperators = []string{"##", "#", "%%", "%", "//", "/", "^^", ",,", "^", ","}
+
+// bashOperation parses the content of a ${...} reference as a bash string operation, when using the Bash dialect
+func (x *expander) bashOperation(content string) (bashOperation, bool) {
+	if x.options.dialect != Bash {
+		return bashOperation{}, false
+	}
+	return parseBashOperation(content)
+}
+
+func parseBashOperation(content string) (bashOperation, bool) {
+	if name, ok := strings.CutPrefix(content, "#"); ok {
+		if name == "" || varNameLength(name) != len(name) {
+			return bashOperation{}, false
+		}
+		return bashOperation{name: name, operator: "length", argStart: len(content)}, true
+	}
+
+	n := varNameLength(content)
+	name, rest := content[:n], content[n:]
+	if name == "" || rest == "" {
+		return bashOperation{}, false
+	}
+	if rest[0] == ':' {
+		// ${VAR:-default}, ${VAR:+replacement} and ${VAR:?error} keep their Compose meaning
+		if len(rest) > 1 && strings.IndexByte("-+?", rest[1]) != -1 {
+			return bashOperation{}, false
+		}
+		return bashOperation{name: name, operator: "substring", arg: rest[1:], argStart: n + 1}, true
+	}
+	for _, operator := range bashOperators {
+		if strings.HasPrefix(rest, operator) {
+			return bashOperation{name: name, operator: operator, arg: rest[len(operator):], argStart: n + len(operator)}, true
+		}
+	}
+	return bashOperation{}, false
+}
+
+// varNameLength returns the length of the variable name at the start of s
+func varNameLength(s string) int {
+	n := 0
+	for n < len(s) && isVarNameChar(s[n]) {
+		n++
+	}
+	return n
+}
+
+// expandBashOperation applies a bash string operation to the value of the variable. Patterns and replacement
+// strings are expanded before use
+func (x *expander) expandBashOperation(op bashOperation, lookup ContextLookupFn, expanded map[string]Location) (string, error) {
+	variable, ok, err := lookup(x.ctx, op.name)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		expanded[op.name] = variable.Location
+	} else {
+		x.undefinedReference(op.name)
+	}
+	value := variable.Value
+	badSubstitution := &ExpansionError{Kind: BadSubstitution, Reference: op.name}
+
+	switch op.operator {
+	case "length":
+		return strconv.Itoa(utf8.RuneCountInString(value)), nil
+	case "substring":
+		result, ok := substring(value, op.arg)
+		if !ok {
+			return "", badSubstitution
+		}
+		return result, nil
+	case "^^", ",,", "^", ",":
+		if op.arg != "" {
+			return "", badSubstitution
+		}
+		return convertCase(value, op.operator), nil
+	}
+
+	// expandArg expands a pattern or replacement string, recording the variables it references
+	expandArg := func(arg string) (string, error) {
+		result, nestedExpanded, err := x.expandString(arg, lookup)
+		for k, v := range nestedExpanded {
+			expanded[k] = v
+		}
+		return result, err
+	}
+
+	pattern, replacement := op.arg, ""
+	var anchor byte
+	if op.operator == "/" || op.operator == "//" {
+		if idx := findOperator(pattern, "/"); idx != -1 {
+			pattern, replacement = pattern[:idx], pattern[idx+1:]
+		}
+		if op.operator == "/" && pattern != "" && (pattern[0] == '#' || pattern[0] == '%') {
+			anchor, pattern = pattern[0], pattern[1:]
+		}
+		if replacement, err = expandArg(replacement); err != nil {
+			return "", err
+		}
+	}
+	pattern, err = expandArg(pattern)
+	if err != nil {
+		return "", err
+	}
+	re, err := globToRegexp(pattern)
+	if err != nil {
+		return "", badSubstitution
+	}
+
+	bounds := runeBoundaries(value)
+	switch op.operator {
+	case "#", "##":
+		longest := op.operator == "##"
+		for k := range bounds {
+			b := bounds[k]
+			if longest {
+				b = bounds[len(bounds)-1-k]
+			}
+			if re.MatchString(value[:b]) {
+				return value[b:], nil
+			}
+		}
+		return value, nil
+	case "%", "%%":
+		longest := op.operator == "%%"
+		for k := range bounds {
+			b := bounds[len(bounds)-1-k]
+			if longest {
+				b = bounds[k]
+			}
+			if re.MatchString(value[b:]) {
+				return value[:b], nil
+			}
+		}
+		return value, nil
+	default:
+		if pattern == "" && anchor == 0 {
+			return value, nil
+		}
+		return replacePattern(value, bounds, re, replacement, op.operator == "//", anchor), nil
+	}
+}
+
+// substring returns the characters of value selected by the "offset" or "offset:length" argument of
+// ${VAR:offset:length}, where a negative offset or length counts from the end of value
+func substring(value, arg string) (string, bool) {
+	offsetArg, lengthArg, hasLength := strings.Cut(arg, ":")
+	offset, err := strconv.Atoi(strings.TrimSpace(offsetArg))
+	if err != nil {
+		return "", false
+	}
+	runes := []rune(value)
+	if offset < 0 {
+		offset += len(runes)
+	}
+	if offset < 0 || offset > len(runes) {
+		return "", true
+	}
+
+	end := len(runes)
+	if hasLength {
+		length, err := strconv.Atoi(strings.TrimSpace(lengthArg))
+		if err != nil {
+			return "", false
+		}
+		if length < 0 {
+			end += length
+			if end < offset {
+				return "", false
+			}
+		} else {
+			end = min(offset+length, end)
+		}
+	}
+	return string(runes[offset:end]), true
+}
+
+// convertCase implements the ${VAR^^}, ${VAR,,}, ${VAR^} and ${VAR,} case conversions
+func convertCase(value, operator string) string {
+	switch operator {
+	case "^^":
+		return strings.ToUpper(value)
+	case ",,":
+		return strings.ToLower(value)
+	}
+	r, size := utf8.DecodeRuneInString(value)
+	if size == 0 {
+		return value
+	}
+	if operator == "^" {
+		return string(unicode.ToUpper(r)) + value[size:]
+	}
+	return string(unicode.ToLower(r)) + value[size:]
+}
+
+// replacePattern replaces the longest match of the pattern at the leftmost position, or every match when all is set.
+// An anchor of '#' or '%' only allows a match at the start or at the end of value
+func replacePattern(value string, bounds []int, re *regexp.Regexp, replacement string, all bool, anchor byte) string {
+	var result strings.Builder
+	for k := 0; k < len(bounds); {
+		start := bounds[k]
+		match := -1
+		if anchor != '#' || start == 0 {
+			for l := len(bounds) - 1; l >= k; l-- {
+				end := bounds[l]
+				if anchor == '%' && end != len(value) {
+					continue
+				}
+				// Unanchored patterns must match at least one character
+				if end == start && anchor == 0 {
+					break
+				}
+				if re.MatchString(value[start:end]) {
+					match = l
+					break
+				}
+			}
+		}
+		if match != -1 {
+			result.WriteString(replacement)
+			if !all {
+				result.WriteString(value[bounds[match]:])
+				return result.String()
+			}
+			k = match
+			continue
+		}
+		if k+1 < len(bounds) {
+			result.WriteString(value[start:bounds[k+1]])
+		}
+		k++
+	}
+	return result.String()
+}
+
+// runeBoundaries returns the byte offsets of the start of each character of value, followed by len(value)
+func runeBoundaries(value string) []int {
+	bounds := make([]int, 0, len(value)+1)
+	for i := range value {
+		bounds = append(bounds, i)
+	}
+	return append(bounds, len(value))
+}
+
+// globToRegexp converts a shell pattern with *, ? and [...] wildcards into a regexp matching a whole string
+func globToRegexp(pattern string) (*regexp.Regexp, error) {
+	var re strings.Builder
+	re.WriteString(`^(?s:`)
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '*':
+			re.WriteString(`.*`)
+		case '?':
+			re.WriteString(`.`)
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+			re.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case '[':
+			j := i + 1
+			if j < len(pattern) && (pattern[j] == '!' || pattern[j] == '^') {
+				j++
+			}
+			if j < len(pattern) && pattern[j] == ']' {
+				j++
+			}
+			for j < len(pattern) && pattern[j] != ']' {
+				j++
+			}
+			if j >= len(pattern) {
+				// No closing bracket, match [ literally
+				re.WriteString(`\[`)
+				continue
+			}
+			class := strings.ReplaceAll(pattern[i+1:j], `\`, `\\`)
+			if class[0] == '!' {
+				class = "^" + class[1:]
+			}
+			re.WriteString("[" + class + "]")
+			i = j
+		default:
+			re.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	re.WriteString(`)$`)
+	return regexp.Compile(re.String())
+}
@@ -0,0 +1,119 @@
+package dotenv_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/compose-spec/dotenv"
+	"gotest.tools/v3/assert"
+)
+
+func TestResolveWithBashDialect(t *testing.T) {
+	type test struct {
+		name   string
+		value  string
+		expect string
+		err    string
+	}
+	tests := []test{
+		{name: "length", value: "${#FILE}", expect: "24"},
+		{name: "length of unset", value: "${#UNSET}", expect: "0"},
+		{name: "length of unicode", value: "${#UNICODE}", expect: "5"},
+		{name: "substring offset", value: "${FILE:5}", expect: "data/archive.tar.gz"},
+		{name: "substring offset and length", value: "${FILE:5:4}", expect: "data"},
+		{name: "substring negative offset", value: "${FILE: -6}", expect: "tar.gz"},
+		{name: "substring negative length", value: "${FILE:5:-7}", expect: "data/archive"},
+		{name: "substring out of range", value: "${FILE:50}", expect: ""},
+		{name: "substring unicode", value: "${UNICODE:1:3}", expect: "éll"},
+		{name: "substring invalid", value: "${FILE:x}", err: "line 1: VALUE: bad substitution of FILE"},
+		{name: "shortest prefix", value: "${FILE#*/}", expect: "srv/data/archive.tar.gz"},
+		{name: "longest prefix", value: "${FILE##*/}", expect: "archive.tar.gz"},
+		{name: "shortest suffix", value: "${FILE%.*}", expect: "/srv/data/archive.tar"},
+		{name: "longest suffix", value: "${FILE%%.*}", expect: "/srv/data/archive"},
+		{name: "unmatched pattern", value: "${FILE#foo}", expect: "/srv/data/archive.tar.gz"},
+		{name: "bracket pattern", value: "${VERSION#[vV]}", expect: "1.2.3"},
+		{name: "negated bracket pattern", value: "${VERSION##*[!0-9]}", expect: "3"},
+		{name: "question mark pattern", value: "${VERSION#??}", expect: ".2.3"},
+		{name: "pattern with dash", value: "${BRANCH#feature-}", expect: "login"},
+		{name: "pattern from variable", value: "${FILE#$ROOT}", expect: "/data/archive.tar.gz"},
+		{name: "escaped pattern", value: `${GLOB#\*}`, expect: "glob*"},
+		{name: "replace first", value: "${VERSION/./-}", expect: "v1-2.3"},
+		{name: "replace all", value: "${VERSION//./-}", expect: "v1-2-3"},
+		{name: "replace longest match", value: "${FILE/a*e/X}", expect: "/srv/dX.tar.gz"},
+		{name: "replace with variable", value: "${VERSION//./$SEP}", expect: "v1_2_3"},
+		{name: "delete all", value: "${VERSION//.}", expect: "v123"},
+		{name: "replace anchored at start", value: "${VERSION/#v/version }", expect: "version 1.2.3"},
+		{name: "replace anchored at end", value: "${VERSION/%3/4}", expect: "v1.2.4"},
+		{name: "anchored pattern not matching", value: "${VERSION/#1/x}", expect: "v1.2.3"},
+		{name: "upper case", value: "${BRANCH^^}", expect: "FEATURE-LOGIN"},
+		{name: "lower case", value: "${MIXED,,}", expect: "mixed case"},
+		{name: "upper case first", value: "${BRANCH^}", expect: "Feature-login"},
+		{name: "lower case first", value: "${MIXED,}", expect: "mIXED Case"},
+		{name: "case conversion with pattern", value: "${MIXED^^[a-z]}", err: "line 1: VALUE: bad substitution of MIXED"},
+		{name: "compose operators", value: "${UNSET:-${BRANCH%-*}}", expect: "feature"},
+		{name: "nested operations", value: "${UNSET-${FILE##*/}}", expect: "archive.tar.gz"},
+	}
+
+	lookup := func(name string) (dotenv.Variable, bool) {
+		values := map[string]string{
+			"FILE":    "/srv/data/archive.tar.gz",
+			"UNICODE": "héllo",
+			"VERSION": "v1.2.3",
+			"BRANCH":  "feature-login",
+			"MIXED":   "MIXED Case",
+			"ROOT":    "/srv",
+			"SEP":     "_",
+			"GLOB":    "*glob*",
+		}
+		value, ok := values[name]
+		return dotenv.Variable{Name: name, Value: value}, ok
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env, err := dotenv.Parse(context.TODO(), strings.NewReader(`VALUE="`+test.value+`"`))
+			assert.NilError(t, err)
+			vars, err := env.Resolve(lookup, dotenv.WithDialect(dotenv.Bash))
+			if test.err != "" {
+				assert.Error(t, err, test.err)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, vars["VALUE"], test.expect)
+		})
+	}
+}
+
+func TestResolveWithComposeDialect(t *testing.T) {
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader("BRANCH=feature-login\nVALUE=\"${BRANCH#feature-}\"\nLENGTH=\"${#BRANCH}\""))
+	assert.NilError(t, err)
+	vars, err := env.Resolve(nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[string]string{
+		"BRANCH": "feature-login",
+		"VALUE":  "",
+		"LENGTH": "",
+	}, vars)
+}
+
+func TestGraphWithBashDialect(t *testing.T) {
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader("NAME=\"${FILE##*/}\"\nBASE=${NAME%$EXT}\nSIZE=\"${#NAME}\"\nFILE=/srv/a.txt\nEXT=.txt"))
+	assert.NilError(t, err)
+
+	graph := env.Graph(dotenv.WithDialect(dotenv.Bash))
+	assert.DeepEqual(t, []dotenv.Reference{
+		{Name: "NAME", Location: ":2", Offset: 0, Definition: ":1"},
+		{Name: "EXT", Location: ":2", Offset: 7, Definition: ":5"},
+	}, graph.Variables[1].References)
+	assert.DeepEqual(t, []dotenv.Reference{{Name: "NAME", Location: ":3", Offset: 0, Definition: ":1"}}, graph.Variables[2].References)
+
+	vars, err := env.Resolve(nil, dotenv.WithDialect(dotenv.Bash), dotenv.WithDependencyOrder())
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[string]string{
+		"NAME": "a.txt",
+		"BASE": "a",
+		"SIZE": "5",
+		"FILE": "/srv/a.txt",
+		"EXT":  ".txt",
+	}, vars)
+}
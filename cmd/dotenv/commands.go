@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/compose-spec/dotenv"
+)
+
+func listCommand(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	var env envFlags
+	fs := newFlagSet("list", "", stderr)
+	env.register(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	resolved, err := env.resolve(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "dotenv: %v\n", err)
+		return 1
+	}
+	for _, v := range resolved.Variables() {
+		fmt.Fprintf(stdout, "%s\t%s\n", v.Name, v.Location)
+	}
+	return 0
+}
+
+func getCommand(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	var env envFlags
+	fs := newFlagSet("get", "KEY", stderr)
+	env.register(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	resolved, err := env.resolve(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "dotenv: %v\n", err)
+		return 1
+	}
+	if v, ok := resolved.Lookup(fs.Arg(0)); ok {
+		fmt.Fprintln(stdout, v.Value)
+		return 0
+	}
+	fmt.Fprintf(stderr, "dotenv: variable %q is not defined\n", fs.Arg(0))
+	return 1
+}
+
+func checkCommand(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	var env envFlags
+	var schemaPath, templatePath string
+	fs := newFlagSet("check", "", stderr)
+	env.register(fs)
+	fs.StringVar(&schemaPath, "schema", "", "validate the variables against a JSON schema or an annotated template .env file at `path`")
+	fs.StringVar(&templatePath, "template", "", "report variables missing from or not declared by the template .env file at `path`, and unfilled placeholders")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	// Report all syntax errors of every file before resolving
+	failed := false
+	actual := &dotenv.EnvFile{}
+	for _, path := range env.paths() {
+		envFile, err := dotenv.ParseFile(ctx, path, dotenv.WithErrorRecovery())
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			failed = true
+			continue
+		}
+		actual.Variables = append(actual.Variables, envFile.Variables...)
+	}
+	if failed {
+		return 1
+	}
+	resolved, err := env.resolve(ctx)
+	if err != nil {
+		for _, err := range unwrapJoined(err) {
+			fmt.Fprintln(stderr, err)
+		}
+		return 1
+	}
+
+	code := 0
+	if templatePath != "" {
+		template, err := dotenv.ParseFile(ctx, templatePath)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		if err := dotenv.CheckTemplate(template, actual); err != nil {
+			for _, err := range unwrapJoined(err) {
+				fmt.Fprintln(stderr, err)
+			}
+			code = 1
+		}
+	}
+
+	if schemaPath == "" {
+		return code
+	}
+	schema, err := readSchema(ctx, schemaPath)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if err := schema.Validate(resolved); err != nil {
+		for _, err := range unwrapJoined(err) {
+			// Deprecated variables are reported as warnings
+			var validationErr *dotenv.ValidationError
+			if errors.As(err, &validationErr) && validationErr.Deprecated {
+				fmt.Fprintf(stderr, "warning: %v\n", err)
+				continue
+			}
+			fmt.Fprintln(stderr, err)
+			code = 1
+		}
+	}
+	return code
+}
+
+// readSchema reads a JSON schema, or the schema declared by the annotations of a template .env file
+func readSchema(ctx context.Context, path string) (*dotenv.Schema, error) {
+	if filepath.Ext(path) == ".json" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		schema, err := dotenv.ReadSchema(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return schema, nil
+	}
+	template, err := dotenv.ParseFile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return dotenv.TemplateSchema(template)
+}
+
+// unwrapJoined returns the errors joined by errors.Join in err, or err itself
+func unwrapJoined(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+func resolveCommand(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	var env envFlags
+	var asJSON bool
+	fs := newFlagSet("resolve", "", stderr)
+	env.register(fs)
+	fs.BoolVar(&asJSON, "json", false, "print the variables as a JSON object")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	resolved, err := env.resolve(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "dotenv: %v\n", err)
+		return 1
+	}
+	values := resolved.Map()
+
+	var out []byte
+	if asJSON {
+		out, err = json.MarshalIndent(values, "", "  ")
+		out = append(out, '\n')
+	} else {
+		out, err = dotenv.Marshal(values)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "dotenv: %v\n", err)
+		return 1
+	}
+	_, _ = stdout.Write(out)
+	return 0
+}
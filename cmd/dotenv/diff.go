@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/compose-spec/dotenv"
+)
+
+func diffCommand(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	var raw, asJSON, mask, noOSEnv, exitCode bool
+	fs := newFlagSet("diff", "OLD NEW", stderr)
+	fs.BoolVar(&raw, "raw", false, "compare raw values as written in the files instead of resolved values")
+	fs.BoolVar(&asJSON, "json", false, "print the differences as JSON")
+	fs.BoolVar(&mask, "mask", false, "mask the values of variables that look like secrets")
+	fs.BoolVar(&noOSEnv, "no-os-env", false, "don't resolve references to variables from the OS environment")
+	fs.BoolVar(&exitCode, "exit-code", false, "exit with status 1 when the files differ")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	var diff *dotenv.EnvDiff
+	if raw {
+		var files [2]*dotenv.EnvFile
+		for i, path := range fs.Args() {
+			envFile, err := dotenv.ParseFile(ctx, path)
+			if err != nil {
+				fmt.Fprintln(stderr, err)
+				return 1
+			}
+			files[i] = envFile
+		}
+		diff = dotenv.DiffFiles(files[0], files[1])
+	} else {
+		var lookup dotenv.ContextLookupFn
+		if !noOSEnv {
+			lookup = dotenv.ContextLookup(dotenv.OSEnv)
+		}
+		var envs [2]*dotenv.ResolvedEnv
+		for i, path := range fs.Args() {
+			resolved, err := dotenv.Load(ctx, []dotenv.Source{{Path: path}}, lookup)
+			if err != nil {
+				fmt.Fprintf(stderr, "dotenv: %v\n", err)
+				return 1
+			}
+			envs[i] = resolved
+		}
+		diff = dotenv.Diff(envs[0], envs[1])
+	}
+	if mask {
+		diff = diff.Masked(dotenv.IsSecret)
+	}
+
+	if asJSON {
+		out, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			fmt.Fprintf(stderr, "dotenv: %v\n", err)
+			return 1
+		}
+		_, _ = stdout.Write(append(out, '\n'))
+	} else if len(diff.Changes) > 0 {
+		fmt.Fprintf(stdout, "--- %s\n+++ %s\n", fs.Arg(0), fs.Arg(1))
+		if err := diff.WriteText(stdout); err != nil {
+			fmt.Fprintf(stderr, "dotenv: %v\n", err)
+			return 1
+		}
+	}
+	if exitCode && len(diff.Changes) > 0 {
+		return 1
+	}
+	return 0
+}
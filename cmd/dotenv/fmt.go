@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/compose-spec/dotenv"
+)
+
+func fmtCommand(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	var write, list, sortKeys bool
+	fs := newFlagSet("fmt", "[FILE...]", stderr)
+	fs.BoolVar(&write, "w", false, "write the result to the file instead of stdout")
+	fs.BoolVar(&list, "l", false, "list files whose formatting differs, exit with an error if any")
+	fs.BoolVar(&sortKeys, "sort", false, "sort variables by name within blocks delimited by blank lines and comments")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	var opts []dotenv.FormatOption
+	if sortKeys {
+		opts = append(opts, dotenv.WithSortedKeys())
+	}
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{".env"}
+	}
+
+	code := 0
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "dotenv: %v\n", err)
+			code = 1
+			continue
+		}
+		formatted, err := dotenv.Format(ctx, bytes.NewReader(content), opts...)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", path, err)
+			code = 1
+			continue
+		}
+
+		switch {
+		case list:
+			if !bytes.Equal(content, formatted) {
+				fmt.Fprintln(stdout, path)
+				code = 1
+			}
+		case write:
+			if !bytes.Equal(content, formatted) {
+				if err := writeFile(path, formatted); err != nil {
+					fmt.Fprintf(stderr, "dotenv: %v\n", err)
+					code = 1
+				}
+			}
+		default:
+			_, _ = stdout.Write(formatted)
+		}
+	}
+	return code
+}
+
+// writeFile replaces the content of an existing file, keeping its permissions
+func writeFile(path string, content []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, info.Mode().Perm())
+}
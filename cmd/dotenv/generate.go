@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/compose-spec/dotenv"
+)
+
+func generateCommand(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	var output, pkg, typeName string
+	fs := newFlagSet("generate", "[TEMPLATE]", stderr)
+	fs.StringVar(&output, "o", "", "write the Go file to `path` instead of stdout")
+	fs.StringVar(&pkg, "package", os.Getenv("GOPACKAGE"), "package `name` of the Go file, set by go generate (default config)")
+	fs.StringVar(&typeName, "type", "Config", "`name` of the generated struct")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+	path := ".env.example"
+	if fs.NArg() == 1 {
+		path = fs.Arg(0)
+	}
+	if pkg == "" {
+		pkg = "config"
+	}
+
+	env, err := dotenv.ParseFile(ctx, path)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	fields, err := configFields(env)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	source, err := generateConfig(generateData{
+		Template: filepath.Base(path),
+		Package:  pkg,
+		Type:     typeName,
+		Fields:   fields,
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "dotenv: %v\n", err)
+		return 1
+	}
+
+	if output == "" {
+		_, _ = stdout.Write(source)
+		return 0
+	}
+	if err := os.WriteFile(output, source, 0o644); err != nil {
+		fmt.Fprintf(stderr, "dotenv: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// configField is a field of the generated struct
+type configField struct {
+	// Variable is the name of the variable and Name the name of the field
+	Variable string
+	Name     string
+	Type     string
+	Tag      string
+	Doc      []string
+}
+
+type generateData struct {
+	Template string
+	Package  string
+	Type     string
+	Fields   []configField
+	Imports  []string
+}
+
+// goTypes maps the scalar types of schemas to Go types
+var goTypes = map[string]string{
+	"string":   "string",
+	"int":      "int",
+	"int64":    "int64",
+	"uint":     "uint",
+	"float":    "float64",
+	"float64":  "float64",
+	"bool":     "bool",
+	"url":      "url.URL",
+	"port":     "uint16",
+	"duration": "time.Duration",
+	"time":     "time.Time",
+	"email":    "string",
+}
+
+// configFields builds the fields of the generated struct from the variables of the template, in order
+func configFields(env *dotenv.EnvFile) ([]configField, error) {
+	schema, err := dotenv.TemplateSchema(env)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []configField
+	seen := make(map[string]bool)
+	names := make(map[string]dotenv.Variable)
+	for _, variable := range env.Variables {
+		if seen[variable.Name] {
+			continue
+		}
+		seen[variable.Name] = true
+		declaration := schema.Variables[variable.Name]
+
+		field := configField{Variable: variable.Name, Name: goName(variable.Name), Type: goType(declaration.Type)}
+		if other, ok := names[field.Name]; ok {
+			return nil, fmt.Errorf("%s: %s: field name %s is already used by %s defined at %s", variable.Location, variable.Name, field.Name, other.Name, other.Location)
+		}
+		names[field.Name] = variable
+		if declaration.Description != "" {
+			field.Doc = strings.Split(declaration.Description, "\n")
+		}
+		options := []string{variable.Name}
+		if declaration.Required {
+			options = append(options, "required")
+		}
+		if declaration.Separator != "" {
+			options = append(options, "sep="+declaration.Separator)
+		}
+		if declaration.Default != nil {
+			// The default value must be the last option
+			options = append(options, "default="+*declaration.Default)
+		}
+		field.Tag = fmt.Sprintf("`env:%q`", strings.Join(options, ","))
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// goType returns the Go type of a schema type, like int, []port or map[string]int
+func goType(typ string) string {
+	if elem, ok := strings.CutPrefix(typ, "[]"); ok {
+		return "[]" + goType(elem)
+	}
+	if kv, ok := strings.CutPrefix(typ, "map["); ok {
+		key, value, _ := strings.Cut(kv, "]")
+		return "map[" + goType(key) + "]" + goType(value)
+	}
+	if goType, ok := goTypes[typ]; ok {
+		return goType
+	}
+	return "string"
+}
+
+// initialisms are written upper case in Go names
+var initialisms = []string{"API", "DB", "DNS", "HTTP", "HTTPS", "ID", "IP", "JSON", "SQL", "SSH", "TCP", "TLS", "TTL", "UDP", "URI", "URL", "UUID", "XML"}
+
+// goName converts a variable name like DATABASE_URL into an exported Go name like DatabaseURL
+func goName(name string) string {
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool {
+		return r == '_' || r == '.' || r == '-'
+	}) {
+		if upper := strings.ToUpper(word); slices.Contains(initialisms, upper) {
+			b.WriteString(upper)
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + strings.ToLower(word[1:]))
+	}
+	if b.Len() == 0 || !unicode.IsLetter(rune(b.String()[0])) {
+		return "V" + b.String()
+	}
+	return b.String()
+}
+
+var configTemplate = template.Must(template.New("config").Parse(`// Code generated by dotenv generate from {{ .Template }}. DO NOT EDIT.
+
+package {{ .Package }}
+
+import (
+	"context"
+{{- range .Imports }}
+	"{{ . }}"
+{{- end }}
+
+	"github.com/compose-spec/dotenv"
+)
+
+// {{ .Type }} holds the variables defined by {{ .Template }}
+type {{ .Type }} struct {
+{{- range .Fields }}
+{{- range .Doc }}
+	// {{ . }}
+{{- end }}
+	{{ .Name }} {{ .Type }} {{ .Tag }}
+{{- end }}
+}
+
+// Load reads the variables of a {{ .Type }} from the OS environment and the .env files at paths, .env by default,
+// which is skipped when missing. Variables set in the OS environment take precedence over the .env files, including
+// in the values referencing them, then later files override earlier ones. References to variables the files don't
+// define are resolved from the OS environment
+func Load(ctx context.Context, paths ...string) (*{{ .Type }}, error) {
+	var sources []dotenv.Source
+	for _, path := range paths {
+		sources = append(sources, dotenv.Source{Path: path})
+	}
+	if len(sources) == 0 {
+		sources = []dotenv.Source{ {Path: ".env", Optional: true} }
+	}
+	var overrides []dotenv.Variable
+	for _, name := range []string{
+{{- range .Fields }}
+		{{ printf "%q" .Variable }},
+{{- end }}
+	} {
+		if v, ok := dotenv.OSEnv(name); ok {
+			overrides = append(overrides, v)
+		}
+	}
+	resolved, err := dotenv.Load(ctx, sources, dotenv.ContextLookup(dotenv.OSEnv), dotenv.WithOverrides(overrides...))
+	if err != nil {
+		return nil, err
+	}
+	var cfg {{ .Type }}
+	if err := dotenv.Unmarshal(resolved, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+`))
+
+// generateConfig renders the Go file declaring the config struct and its Load function
+func generateConfig(data generateData) ([]byte, error) {
+	for _, field := range data.Fields {
+		for _, pkg := range []string{"net/url", "time"} {
+			if strings.Contains(field.Type, pkg[strings.LastIndex(pkg, "/")+1:]+".") && !slices.Contains(data.Imports, pkg) {
+				data.Imports = append(data.Imports, pkg)
+			}
+		}
+	}
+	slices.Sort(data.Imports)
+
+	var buf bytes.Buffer
+	if err := configTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
@@ -0,0 +1,136 @@
+// Command dotenv inspects, checks and resolves .env files
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/compose-spec/dotenv"
+)
+
+const usage = `Usage: dotenv <command> [options] [arguments]
+
+Commands:
+  list       List the variables defined by the .env files
+  get        Print the resolved value of a variable
+  check      Parse and resolve the .env files, exit with an error on failure
+  resolve    Print the resolved variables as .env or JSON
+  fmt        Format .env files
+  run        Run a command with the resolved variables in its environment
+  generate   Generate a typed Go config struct from an annotated .env template
+  diff       Print the differences between two .env files
+
+Run 'dotenv <command> -h' for the options of a command.
+`
+
+// command is a dotenv subcommand, returning the process exit code
+type command func(ctx context.Context, args []string, stdout, stderr io.Writer) int
+
+var commands = map[string]command{
+	"list":     listCommand,
+	"get":      getCommand,
+	"check":    checkCommand,
+	"resolve":  resolveCommand,
+	"fmt":      fmtCommand,
+	"run":      runCommand,
+	"generate": generateCommand,
+	"diff":     diffCommand,
+}
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	if args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		fmt.Fprint(stdout, usage)
+		return 0
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "dotenv: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+	return cmd(ctx, args[1:], stdout, stderr)
+}
+
+// newFlagSet creates the flag set of a subcommand, reporting usage errors to stderr
+func newFlagSet(name, args string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: dotenv %s [options] %s\n\nOptions:\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses args and returns the exit code to use when the command must stop
+func parseFlags(fs *flag.FlagSet, args []string) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0, false
+		}
+		return 2, false
+	}
+	return 0, true
+}
+
+// stringList is a flag that can be repeated
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// envFlags are the options shared by commands reading .env files
+type envFlags struct {
+	files   stringList
+	noOSEnv bool
+	strict  bool
+}
+
+func (f *envFlags) register(fs *flag.FlagSet) {
+	fs.Var(&f.files, "env-file", "`path` of an .env file, can be repeated, later files override earlier ones (default .env)")
+	fs.Var(&f.files, "f", "shorthand for --env-file")
+	fs.BoolVar(&f.noOSEnv, "no-os-env", false, "don't resolve references to variables from the OS environment")
+	fs.BoolVar(&f.strict, "strict", false, "fail on references to undefined variables without a default value")
+}
+
+// paths returns the .env files to read
+func (f *envFlags) paths() []string {
+	if len(f.files) == 0 {
+		return []string{".env"}
+	}
+	return f.files
+}
+
+// resolve loads the .env files in order: later files override earlier ones and can reference their variables
+func (f *envFlags) resolve(ctx context.Context, opts ...dotenv.ResolveOption) (*dotenv.ResolvedEnv, error) {
+	var sources []dotenv.Source
+	for _, path := range f.paths() {
+		sources = append(sources, dotenv.Source{Path: path})
+	}
+	var lookup dotenv.ContextLookupFn
+	if !f.noOSEnv {
+		lookup = dotenv.ContextLookup(dotenv.OSEnv)
+	}
+	if f.strict {
+		opts = append(opts, dotenv.WithStrict())
+	}
+	return dotenv.Load(ctx, sources, lookup, opts...)
+}
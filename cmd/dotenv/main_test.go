@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func writeEnvFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	assert.NilError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestCommands(t *testing.T) {
+	t.Setenv("DOTENV_TEST_HOME", "/home/test")
+	base := writeEnvFile(t, "base.env", "HOST=localhost\nPORT=5432\nHOME_DIR=${DOTENV_TEST_HOME}\n")
+	override := writeEnvFile(t, "override.env", "HOST=db\nURL=postgres://${HOST}:${PORT}\n")
+	invalid := writeEnvFile(t, "invalid.env", "FOO=bar\nINVALID\n1FOO=bar\n")
+	required := writeEnvFile(t, "required.env", "FOO=${UNSET:?UNSET must be set}\n")
+	typos := writeEnvFile(t, "typos.env", "HOST=db\nURL=$HOTS:$PROT\nPORT=${DB_PORT:-5432}\n")
+	schema := writeEnvFile(t, "schema.json", `{"variables": {"PORT": {"type": "port"}, "HOST": {"deprecated": "use URL"}, "URL": {"required": true}}}`)
+	example := writeEnvFile(t, ".env.example", "# @type int\nHOST=\n# @type url\nURL=\n")
+
+	type test struct {
+		name   string
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}
+	tests := []test{
+		{
+			name:   "list",
+			args:   []string{"list", "-f", base, "--env-file", override},
+			stdout: "HOST\t" + override + ":1\nPORT\t" + base + ":2\nHOME_DIR\t" + base + ":3\nURL\t" + override + ":2\n",
+		},
+		{
+			name:   "get",
+			args:   []string{"get", "-f", base, "-f", override, "URL"},
+			stdout: "postgres://db:5432\n",
+		},
+		{
+			name:   "get undefined",
+			args:   []string{"get", "-f", base, "UNDEFINED"},
+			code:   1,
+			stderr: "dotenv: variable \"UNDEFINED\" is not defined\n",
+		},
+		{
+			name:   "get without os env",
+			args:   []string{"get", "-f", base, "--no-os-env", "HOME_DIR"},
+			stdout: "\n",
+		},
+		{
+			name:   "resolve",
+			args:   []string{"resolve", "-f", base, "-f", override},
+			stdout: "HOME_DIR=/home/test\nHOST=db\nPORT=5432\nURL=postgres://db:5432\n",
+		},
+		{
+			name:   "resolve json",
+			args:   []string{"resolve", "-f", base, "--json"},
+			stdout: "{\n  \"HOME_DIR\": \"/home/test\",\n  \"HOST\": \"localhost\",\n  \"PORT\": \"5432\"\n}\n",
+		},
+		{
+			name: "check",
+			args: []string{"check", "-f", base, "-f", override},
+		},
+		{
+			name:   "check syntax errors",
+			args:   []string{"check", "-f", invalid},
+			code:   1,
+			stderr: invalid + ": line 2: no separator found in line: INVALID\n" + invalid + ": line 3: invalid variable name \"1FOO\"\n",
+		},
+		{
+			name:   "check resolution errors",
+			args:   []string{"check", "-f", required},
+			code:   1,
+			stderr: required + ":1: UNSET must be set\n",
+		},
+		{
+			name: "check undefined variables",
+			args: []string{"check", "-f", typos},
+		},
+		{
+			name:   "check strict",
+			args:   []string{"check", "--strict", "-f", typos},
+			code:   1,
+			stderr: typos + ":2: URL: undefined variable HOTS\n" + typos + ":2: URL: undefined variable PROT\n",
+		},
+		{
+			name:   "check schema",
+			args:   []string{"check", "-f", base, "--schema", schema},
+			code:   1,
+			stderr: "warning: " + base + ":1: HOST: deprecated: use URL\n" + "URL: required variable is not set\n",
+		},
+		{
+			name:   "check template schema",
+			args:   []string{"check", "-f", base, "-f", override, "--schema", example},
+			code:   1,
+			stderr: override + ":1: HOST: invalid int value \"db\"\n",
+		},
+		{
+			name:   "check template",
+			args:   []string{"check", "-f", base, "-f", override, "--template", example},
+			code:   1,
+			stderr: base + ":2: PORT: not declared by the template\n" + base + ":3: HOME_DIR: not declared by the template\n",
+		},
+		{
+			name:   "unknown command",
+			args:   []string{"unknown"},
+			code:   2,
+			stderr: "dotenv: unknown command \"unknown\"\n\n" + usage,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			code := run(context.TODO(), test.args, &stdout, &stderr)
+			assert.Equal(t, code, test.code)
+			assert.Equal(t, stdout.String(), test.stdout)
+			assert.Equal(t, stderr.String(), test.stderr)
+		})
+	}
+}
+
+func TestFmtCommand(t *testing.T) {
+	path := writeEnvFile(t, ".env", "B = b\nA : \"a\"\n")
+
+	var stdout, stderr strings.Builder
+	assert.Equal(t, run(context.TODO(), []string{"fmt", "-l", path}, &stdout, &stderr), 1)
+	assert.Equal(t, stdout.String(), path+"\n")
+
+	stdout.Reset()
+	assert.Equal(t, run(context.TODO(), []string{"fmt", "-w", "-sort", path}, &stdout, &stderr), 0)
+	content, err := os.ReadFile(path)
+	assert.NilError(t, err)
+	assert.Equal(t, string(content), "A=a\nB=b\n")
+
+	assert.Equal(t, run(context.TODO(), []string{"fmt", "-l", path}, &stdout, &stderr), 0)
+	assert.Equal(t, stdout.String(), "")
+	assert.Equal(t, stderr.String(), "")
+}
+
+func TestGenerateCommand(t *testing.T) {
+	t.Setenv("GOPACKAGE", "")
+	template := writeEnvFile(t, ".env.example", strings.Join([]string{
+		"# Name of the application",
+		"APP_NAME=api",
+		"",
+		"# Port to listen on @type int @default 8080",
+		"PORT=",
+		"# @type url @required",
+		"DATABASE_URL=postgres://localhost/app",
+		"# Allowed origins",
+		"# @type []string @sep ;",
+		"CORS_ORIGINS=",
+		"# @type duration",
+		"REQUEST_TIMEOUT=30s",
+		"# @type map[string]int",
+		"LIMITS=",
+		"",
+	}, "\n"))
+
+	var stdout, stderr strings.Builder
+	assert.Equal(t, run(context.TODO(), []string{"generate", "-package", "app", template}, &stdout, &stderr), 0)
+	assert.Equal(t, stderr.String(), "")
+	golden, err := os.ReadFile(filepath.Join("testdata", "config.go.golden"))
+	assert.NilError(t, err)
+	assert.Equal(t, stdout.String(), string(golden))
+
+	output := filepath.Join(t.TempDir(), "config.go")
+	stdout.Reset()
+	assert.Equal(t, run(context.TODO(), []string{"generate", "-o", output, "-type", "Settings", template}, &stdout, &stderr), 0)
+	content, err := os.ReadFile(output)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(content), "package config\n"))
+	assert.Assert(t, strings.Contains(string(content), "func Load(ctx context.Context, paths ...string) (*Settings, error) {"))
+
+	invalid := writeEnvFile(t, "invalid.env", "FOO=bar\n# @type int @secret\nPORT=80\n")
+	assert.Equal(t, run(context.TODO(), []string{"generate", invalid}, &stdout, &stderr), 1)
+	assert.Equal(t, stderr.String(), invalid+":3: PORT: unknown annotation @secret\n")
+
+	collision := writeEnvFile(t, "collision.env", "FOO_BAR=1\nFOO.BAR=2\n")
+	stderr.Reset()
+	assert.Equal(t, run(context.TODO(), []string{"generate", collision}, &stdout, &stderr), 1)
+	assert.Equal(t, stderr.String(), collision+":2: FOO.BAR: field name FooBar is already used by FOO_BAR defined at "+collision+":1\n")
+}
+
+func TestDiffCommand(t *testing.T) {
+	from := writeEnvFile(t, "old.env", "HOST=localhost\nURL=http://${HOST}\nAPI_KEY=abc\n")
+	to := writeEnvFile(t, "new.env", "HOST=db\nURL=http://${HOST}\nAPI_KEY=def\nDEBUG=true\n")
+
+	var stdout, stderr strings.Builder
+	assert.Equal(t, run(context.TODO(), []string{"diff", "--mask", "--exit-code", from, to}, &stdout, &stderr), 1)
+	assert.Equal(t, stderr.String(), "")
+	assert.Equal(t, stdout.String(), strings.Join([]string{
+		"--- " + from,
+		"+++ " + to,
+		"-HOST=localhost",
+		"+HOST=db",
+		"-URL=http://localhost",
+		"+URL=http://db # changed upstream: HOST",
+		"-API_KEY=********",
+		"+API_KEY=********",
+		"+DEBUG=true",
+		"",
+	}, "\n"))
+
+	stdout.Reset()
+	assert.Equal(t, run(context.TODO(), []string{"diff", "--raw", "--json", from, to}, &stdout, &stderr), 0)
+	assert.Assert(t, strings.Contains(stdout.String(), `"raw": true`))
+	assert.Assert(t, !strings.Contains(stdout.String(), `"name": "URL"`))
+
+	stdout.Reset()
+	assert.Equal(t, run(context.TODO(), []string{"diff", "--exit-code", from, from}, &stdout, &stderr), 0)
+	assert.Equal(t, stdout.String(), "")
+}
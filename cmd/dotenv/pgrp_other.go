@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd)
+
+package main
+
+import "os/exec"
+
+// newProcessGroup leaves the command in the process group of dotenv
+func newProcessGroup(*exec.Cmd) func() {
+	return func() {}
+}
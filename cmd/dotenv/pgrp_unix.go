@@ -0,0 +1,46 @@
+//go:build linux || darwin || freebsd
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"unsafe"
+)
+
+// newProcessGroup runs the command in a process group of its own, so that signals sent by the terminal to its
+// foreground process group don't reach the command in addition to those forwarded by dotenv.
+// When dotenv is in the foreground of the terminal of its standard input, the terminal is handed to the command so
+// that it can read it and receives the signals of the terminal directly. The returned function gives the terminal back
+// to dotenv once the command exited
+func newProcessGroup(cmd *exec.Cmd) func() {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	fd := os.Stdin.Fd()
+	pgrp, ok := foregroundGroup(fd)
+	if !ok || pgrp != syscall.Getpgrp() {
+		return func() {}
+	}
+	cmd.SysProcAttr.Foreground = true
+	cmd.SysProcAttr.Ctty = int(fd)
+	return func() {
+		// Background process groups are stopped by SIGTTOU when taking the terminal
+		signal.Ignore(syscall.SIGTTOU)
+		defer signal.Reset(syscall.SIGTTOU)
+		setForegroundGroup(fd, pgrp)
+	}
+}
+
+// foregroundGroup returns the foreground process group of the terminal fd, false if fd isn't a terminal
+func foregroundGroup(fd uintptr) (int, bool) {
+	var pgrp int32
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TIOCGPGRP, uintptr(unsafe.Pointer(&pgrp)))
+	return int(pgrp), errno == 0
+}
+
+// setForegroundGroup makes pgrp the foreground process group of the terminal fd
+func setForegroundGroup(fd uintptr, pgrp int) {
+	group := int32(pgrp)
+	_, _, _ = syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TIOCSPGRP, uintptr(unsafe.Pointer(&group)))
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+
+	"github.com/compose-spec/dotenv"
+)
+
+func runCommand(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	var env envFlags
+	var override, clean bool
+	fs := newFlagSet("run", "-- COMMAND [ARG...]", stderr)
+	env.register(fs)
+	fs.BoolVar(&override, "override", false, "let the .env files override variables already set in the OS environment")
+	fs.BoolVar(&clean, "clean", false, "run the command with the variables of the .env files only, without inheriting the OS environment")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	// Unless overridden, variables of the OS environment win, including in the values referencing them
+	var opts []dotenv.ResolveOption
+	if !clean && !override {
+		opts = append(opts, dotenv.WithOverrides(osVariables()...))
+	}
+	resolved, err := env.resolve(ctx, opts...)
+	if err != nil {
+		fmt.Fprintf(stderr, "dotenv: %v\n", err)
+		return 1
+	}
+
+	var environ []string
+	if !clean {
+		environ = os.Environ()
+	}
+	for _, v := range resolved.Variables() {
+		if _, set := os.LookupEnv(v.Name); set && !clean && !override {
+			continue
+		}
+		environ = append(environ, v.Name+"="+v.Value)
+	}
+
+	cmd := exec.Command(fs.Arg(0), fs.Args()[1:]...)
+	cmd.Env = environ
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	// Forward signals received by dotenv to the command, which decides whether to stop.
+	// Signals are caught before the command starts, so that none stops dotenv while the command runs.
+	// The command runs in its own process group, so that it receives each signal once
+	restoreTerminal := newProcessGroup(cmd)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+	if err := cmd.Start(); err != nil {
+		signal.Stop(signals)
+		restoreTerminal()
+		fmt.Fprintf(stderr, "dotenv: %v\n", err)
+		if errors.Is(err, exec.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+			return 127
+		}
+		return 126
+	}
+	forwarded := make(chan struct{})
+	go func() {
+		defer close(forwarded)
+		for sig := range signals {
+			_ = cmd.Process.Signal(sig)
+		}
+	}()
+
+	err = cmd.Wait()
+	restoreTerminal()
+	signal.Stop(signals)
+	close(signals)
+	<-forwarded
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitCode(exitErr)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "dotenv: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// osVariables returns the variables of the OS environment
+func osVariables() []dotenv.Variable {
+	var variables []dotenv.Variable
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if v, ok := dotenv.OSEnv(name); ok {
+			variables = append(variables, v)
+		}
+	}
+	return variables
+}
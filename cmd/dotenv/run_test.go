@@ -0,0 +1,88 @@
+//go:build unix
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestRunCommand(t *testing.T) {
+	t.Setenv("DOTENV_TEST_SHARED", "from-os")
+	t.Setenv("DOTENV_TEST_OS_ONLY", "os")
+	base := writeEnvFile(t, "base.env", "DOTENV_TEST_SHARED=from-file\nDOTENV_TEST_HOST=localhost\n")
+	override := writeEnvFile(t, "override.env", "DOTENV_TEST_URL=http://${DOTENV_TEST_HOST}\n")
+	script := `echo "$DOTENV_TEST_SHARED $DOTENV_TEST_URL ${DOTENV_TEST_OS_ONLY:-unset}"`
+	references := writeEnvFile(t, "references.env", "DOTENV_TEST_SHARED=from-file\nDOTENV_TEST_REF=ref-$DOTENV_TEST_SHARED\n")
+	referencesScript := `echo "$DOTENV_TEST_SHARED $DOTENV_TEST_REF"`
+
+	type test struct {
+		name   string
+		args   []string
+		code   int
+		stdout string
+	}
+	tests := []test{
+		{
+			name:   "os environment wins by default",
+			args:   []string{"run", "-f", base, "-f", override, "--", "sh", "-c", script},
+			stdout: "from-os http://localhost os\n",
+		},
+		{
+			name:   "override",
+			args:   []string{"run", "-f", base, "-f", override, "--override", "--", "sh", "-c", script},
+			stdout: "from-file http://localhost os\n",
+		},
+		{
+			name:   "clean environment",
+			args:   []string{"run", "-f", base, "-f", override, "--clean", "--", "/bin/sh", "-c", script},
+			stdout: "from-file http://localhost unset\n",
+		},
+		{
+			name:   "references follow the os environment",
+			args:   []string{"run", "-f", references, "--", "sh", "-c", referencesScript},
+			stdout: "from-os ref-from-os\n",
+		},
+		{
+			name:   "references follow overrides",
+			args:   []string{"run", "-f", references, "--override", "--", "sh", "-c", referencesScript},
+			stdout: "from-file ref-from-file\n",
+		},
+		{
+			name: "exit code",
+			args: []string{"run", "-f", base, "--", "sh", "-c", "exit 3"},
+			code: 3,
+		},
+		{
+			name: "killed by signal",
+			args: []string{"run", "-f", base, "--", "sh", "-c", "kill -TERM $$"},
+			code: 143,
+		},
+		{
+			name: "forwarded signal",
+			args: []string{"run", "-f", base, "--", "sh", "-c", "trap 'exit 7' USR1; kill -USR1 $PPID; while :; do sleep 0.1; done"},
+			code: 7,
+		},
+		{
+			name: "own process group",
+			args: []string{"run", "-f", base, "--", "sh", "-c", "kill -0 -$$"},
+		},
+		{
+			name: "command not found",
+			args: []string{"run", "-f", base, "--", "dotenv-test-command-not-found"},
+			code: 127,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			code := run(context.TODO(), test.args, &stdout, &stderr)
+			assert.Equal(t, code, test.code, stderr.String())
+			assert.Equal(t, stdout.String(), test.stdout)
+		})
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// forwardedSignals are the signals relayed to the command started by dotenv run
+var forwardedSignals = []os.Signal{os.Interrupt}
+
+// exitCode returns the exit code of a command
+func exitCode(err *exec.ExitError) int {
+	return err.ExitCode()
+}
@@ -0,0 +1,45 @@
+package dotenv
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// CommandRunner runs the commands of $(command) substitutions and returns their output
+type CommandRunner interface {
+	Run(ctx context.Context, command string) (string, error)
+}
+
+// CommandRunnerFunc is a function implementing CommandRunner
+type CommandRunnerFunc func(ctx context.Context, command string) (string, error)
+
+func (f CommandRunnerFunc) Run(ctx context.Context, command string) (string, error) {
+	return f(ctx, command)
+}
+
+// WithCommandRunner enables $(command) substitutions in unquoted and double-quoted values, running commands
+// with runner. The command text is passed as is, without variable expansion, and trailing newlines are removed
+// from the output. Without a CommandRunner, $(command) is kept literally: commands are never run by default
+func WithCommandRunner(runner CommandRunner) ResolveOption {
+	return func(o *resolveOptions) {
+		o.runner = runner
+	}
+}
+
+// ShellCommandRunner runs commands with "sh -c" in the current working directory and environment
+var ShellCommandRunner CommandRunner = CommandRunnerFunc(func(ctx context.Context, command string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return string(out), nil
+})
@@ -0,0 +1,99 @@
+package dotenv_test
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/compose-spec/dotenv"
+	"gotest.tools/v3/assert"
+)
+
+func TestResolveWithCommandRunner(t *testing.T) {
+	input := strings.Join([]string{
+		"COMMIT=$(git rev-parse HEAD)",
+		`TAG="v-$(git describe --tags)"`,
+		"LITERAL='$(git rev-parse HEAD)'",
+		`ESCAPED=\$(git rev-parse HEAD)`,
+		"NESTED=$(echo $(date))",
+		`QUOTED=$(echo ")")`,
+		`BRACED=$(printf '%s' "${A:-)}")`,
+		`SINGLE=$(echo ')' "$(echo '"')")`,
+		"UNTERMINATED=$(git",
+	}, "\n")
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader(input))
+	assert.NilError(t, err)
+
+	var commands []string
+	runner := dotenv.CommandRunnerFunc(func(ctx context.Context, command string) (string, error) {
+		commands = append(commands, command)
+		return "output of " + command + "\n\n", nil
+	})
+	vars, err := env.Resolve(nil, dotenv.WithCommandRunner(runner))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[string]string{
+		"COMMIT":       "output of git rev-parse HEAD",
+		"TAG":          "v-output of git describe --tags",
+		"LITERAL":      "$(git rev-parse HEAD)",
+		"ESCAPED":      "$(git rev-parse HEAD)",
+		"NESTED":       "output of echo $(date)",
+		"QUOTED":       `output of echo ")"`,
+		"BRACED":       `output of printf '%s' "${A:-)}"`,
+		"SINGLE":       `output of echo ')' "$(echo '"')"`,
+		"UNTERMINATED": "$(git",
+	}, vars)
+	assert.DeepEqual(t, []string{
+		"git rev-parse HEAD",
+		"git describe --tags",
+		"echo $(date)",
+		`echo ")"`,
+		`printf '%s' "${A:-)}"`,
+		`echo ')' "$(echo '"')"`,
+	}, commands)
+	// References in commands are left to the CommandRunner
+	assert.DeepEqual(t, []dotenv.Reference{}, env.Graph().Variables[6].References)
+}
+
+func TestResolveWithoutCommandRunner(t *testing.T) {
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader("HOST=db\nCOMMIT=$(echo $HOST)\nTAG=\"v-$(git describe)\""))
+	assert.NilError(t, err)
+	vars, err := env.Resolve(nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[string]string{
+		"HOST":   "db",
+		"COMMIT": "$(echo $HOST)",
+		"TAG":    "v-$(git describe)",
+	}, vars)
+	assert.DeepEqual(t, []dotenv.Reference{}, env.Graph().Variables[1].References)
+}
+
+func TestResolveWithFailingCommand(t *testing.T) {
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader("COMMIT=$(git rev-parse HEAD)"), dotenv.WithSourceName(".env"))
+	assert.NilError(t, err)
+	failure := errors.New("not a git repository")
+	_, err = env.Resolve(nil, dotenv.WithCommandRunner(dotenv.CommandRunnerFunc(func(context.Context, string) (string, error) {
+		return "", failure
+	})))
+	assert.Error(t, err, ".env:1: COMMIT: $(git rev-parse HEAD): not a git repository")
+	assert.Assert(t, errors.Is(err, failure))
+	var expansionErr *dotenv.ExpansionError
+	assert.Assert(t, errors.As(err, &expansionErr))
+	assert.Equal(t, expansionErr.Kind, dotenv.CommandFailed)
+	assert.Equal(t, expansionErr.Location, dotenv.Location(".env:1"))
+	assert.Equal(t, expansionErr.Column, 1)
+	assert.Equal(t, expansionErr.Snippet, "$(git rev-parse HEAD)")
+}
+
+func TestShellCommandRunner(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	out, err := dotenv.ShellCommandRunner.Run(context.TODO(), "echo hello")
+	assert.NilError(t, err)
+	assert.Equal(t, out, "hello\n")
+
+	_, err = dotenv.ShellCommandRunner.Run(context.TODO(), "echo failure >&2; exit 3")
+	assert.Error(t, err, "exit status 3: failure")
+}
@@ -0,0 +1,324 @@
+package dotenv
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ChangeKind identifies how a variable differs between two environments
+type ChangeKind int
+
+const (
+	// Added reports a variable only defined by the new environment
+	Added ChangeKind = iota + 1
+	// Removed reports a variable only defined by the old environment
+	Removed
+	// Changed reports a variable whose value changed
+	Changed
+	// UpstreamChanged reports a variable whose raw value is the same but whose resolved value changed,
+	// because a variable it references changed
+	UpstreamChanged
+)
+
+var changeKinds = map[ChangeKind]string{
+	Added:           "added",
+	Removed:         "removed",
+	Changed:         "changed",
+	UpstreamChanged: "upstream",
+}
+
+// String returns the name of the change kind, as used in JSON
+func (k ChangeKind) String() string {
+	if name, ok := changeKinds[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+func (k ChangeKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+func (k *ChangeKind) UnmarshalText(text []byte) error {
+	for kind, name := range changeKinds {
+		if name == string(text) {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown change kind %q", text)
+}
+
+// EnvDiff lists the differences between two environments
+type EnvDiff struct {
+	// Raw is true when raw values are compared, false when resolved values are compared
+	Raw bool `json:"raw"`
+	// Changes are the changed variables, in the order of the new environment, then removed variables
+	// in the order of the old environment
+	Changes []Change `json:"changes"`
+}
+
+// Change is a variable that differs between two environments
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+	Name string     `json:"name"`
+	// Old and New are the definitions of the variable in the old and new environments, nil when it isn't defined
+	Old *ChangeValue `json:"old,omitempty"`
+	New *ChangeValue `json:"new,omitempty"`
+	// Upstream names the referenced variables whose change caused an UpstreamChanged change
+	Upstream []string `json:"upstream,omitempty"`
+}
+
+// ChangeValue is the value of a changed variable in one of the compared environments
+type ChangeValue struct {
+	Value    string   `json:"value"`
+	Location Location `json:"location,omitempty"`
+	// Expanded names the variables expanded to compute Value, directly or through other variables
+	Expanded []string `json:"expanded,omitempty"`
+	// Masked is true when Value was replaced by a mask
+	Masked bool `json:"masked,omitempty"`
+	// Quoted is the quote style of the definition, used to write raw values as in the file
+	Quoted QuoteStyle `json:"-"`
+}
+
+// DiffOption configures Diff
+type DiffOption func(*diffOptions)
+
+type diffOptions struct {
+	raw bool
+}
+
+// WithRawValues makes Diff compare raw values, as written in the files, instead of resolved values
+func WithRawValues() DiffOption {
+	return func(o *diffOptions) {
+		o.raw = true
+	}
+}
+
+// Diff returns the differences from an old to a new resolved environment. Resolved values are compared, unless
+// WithRawValues is set. Variables whose raw value didn't change but whose resolved value did are reported as
+// UpstreamChanged, with the variables they expanded according to Variable.Expanded which changed or are defined
+// by neither environment, like variables of the OS environment
+func Diff(from, to *ResolvedEnv, opts ...DiffOption) *EnvDiff {
+	var options diffOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return diffVariables(from.Variables(), to.Variables(), options.raw)
+}
+
+// DiffFiles returns the differences from the raw values of an old to a new .env file, without resolving them.
+// When a file defines a variable multiple times, its last definition is compared
+func DiffFiles(from, to *EnvFile) *EnvDiff {
+	return diffVariables(lastDefinitions(from.Variables), lastDefinitions(to.Variables), true)
+}
+
+// lastDefinitions returns the last definition of each variable, in the order the variables are first defined
+func lastDefinitions(variables []Variable) []Variable {
+	index := make(map[string]int)
+	var definitions []Variable
+	for _, v := range variables {
+		if i, ok := index[v.Name]; ok {
+			definitions[i] = v
+			continue
+		}
+		index[v.Name] = len(definitions)
+		definitions = append(definitions, v)
+	}
+	return definitions
+}
+
+func diffVariables(oldVariables, newVariables []Variable, raw bool) *EnvDiff {
+	value := func(v Variable) string {
+		if raw {
+			return v.RawValue
+		}
+		return v.Value
+	}
+	oldIndex := make(map[string]Variable, len(oldVariables))
+	for _, v := range oldVariables {
+		oldIndex[v.Name] = v
+	}
+	newIndex := make(map[string]Variable, len(newVariables))
+	for _, v := range newVariables {
+		newIndex[v.Name] = v
+	}
+	// upstream reports whether the variable name differs between the environments, or is defined by neither
+	// of them and may differ
+	upstream := func(name string) bool {
+		o, inOld := oldIndex[name]
+		n, inNew := newIndex[name]
+		return inOld != inNew || value(o) != value(n) || !inOld && !inNew
+	}
+	oldValue := func(v Variable) *ChangeValue {
+		return &ChangeValue{Value: value(v), Location: v.Location, Expanded: expandedNames(v, oldIndex), Quoted: v.Quoted}
+	}
+	newValue := func(v Variable) *ChangeValue {
+		return &ChangeValue{Value: value(v), Location: v.Location, Expanded: expandedNames(v, newIndex), Quoted: v.Quoted}
+	}
+
+	diff := &EnvDiff{Raw: raw, Changes: []Change{}}
+	for _, n := range newVariables {
+		o, ok := oldIndex[n.Name]
+		switch {
+		case !ok:
+			diff.Changes = append(diff.Changes, Change{Kind: Added, Name: n.Name, New: newValue(n)})
+		case value(o) != value(n):
+			change := Change{
+				Kind: Changed,
+				Name: n.Name,
+				Old:  oldValue(o),
+				New:  newValue(n),
+			}
+			if o.RawValue == n.RawValue {
+				for _, expanded := range []map[string]Location{n.Expanded, o.Expanded} {
+					for name := range expanded {
+						if upstream(name) && !slices.Contains(change.Upstream, name) {
+							change.Upstream = append(change.Upstream, name)
+						}
+					}
+				}
+				slices.Sort(change.Upstream)
+				if len(change.Upstream) > 0 {
+					change.Kind = UpstreamChanged
+				}
+			}
+			diff.Changes = append(diff.Changes, change)
+		}
+	}
+	for _, o := range oldVariables {
+		if _, ok := newIndex[o.Name]; !ok {
+			diff.Changes = append(diff.Changes, Change{Kind: Removed, Name: o.Name, Old: oldValue(o)})
+		}
+	}
+	return diff
+}
+
+// expandedNames returns the sorted names of the variables expanded to compute the value of v, directly or through
+// the variables of index
+func expandedNames(v Variable, index map[string]Variable) []string {
+	var names []string
+	pending := slices.Collect(maps.Keys(v.Expanded))
+	for len(pending) > 0 {
+		name := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if name == v.Name || slices.Contains(names, name) {
+			continue
+		}
+		names = append(names, name)
+		pending = append(pending, slices.Collect(maps.Keys(index[name].Expanded))...)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// secretWords are the words of variable names holding secrets
+var secretWords = []string{"PASSWORD", "PASSWD", "PASS", "PWD", "SECRET", "TOKEN", "KEY", "APIKEY", "CREDENTIALS", "CREDENTIAL", "PRIVATE", "AUTH", "SALT", "DSN", "CERT"}
+
+// IsSecret returns whether the variable name looks like it holds a secret, like DB_PASSWORD or API_KEY
+func IsSecret(name string) bool {
+	for _, word := range strings.FieldsFunc(strings.ToUpper(name), func(r rune) bool {
+		return r == '_' || r == '.' || r == '-'
+	}) {
+		if slices.Contains(secretWords, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// Masked returns a copy of the diff where values are masked, to share the diff without leaking secrets.
+// The values of the variables for which secret returns true, like IsSecret, are masked, as well as values
+// expanding such a variable, or containing the value of such a variable
+func (d *EnvDiff) Masked(secret func(name string) bool) *EnvDiff {
+	var secretValues []string
+	for _, change := range d.Changes {
+		if secret(change.Name) {
+			for _, v := range []*ChangeValue{change.Old, change.New} {
+				if v != nil && v.Value != "" {
+					secretValues = append(secretValues, v.Value)
+				}
+			}
+		}
+	}
+	// leaks reports whether v may reveal a secret
+	leaks := func(v *ChangeValue) bool {
+		if v == nil {
+			return false
+		}
+		if slices.ContainsFunc(v.Expanded, secret) {
+			return true
+		}
+		return slices.ContainsFunc(secretValues, func(s string) bool {
+			return strings.Contains(v.Value, s)
+		})
+	}
+
+	masked := &EnvDiff{Raw: d.Raw, Changes: make([]Change, len(d.Changes))}
+	for i, change := range d.Changes {
+		if secret(change.Name) || leaks(change.Old) || leaks(change.New) {
+			change.Old = maskValue(change.Old)
+			change.New = maskValue(change.New)
+		}
+		masked.Changes[i] = change
+	}
+	return masked
+}
+
+// mask replaces masked values
+const mask = "********"
+
+func maskValue(v *ChangeValue) *ChangeValue {
+	if v == nil {
+		return nil
+	}
+	return &ChangeValue{Value: mask, Location: v.Location, Expanded: v.Expanded, Masked: true}
+}
+
+// WriteText writes the diff in a unified diff format: each removed definition on a line starting with -,
+// each added definition on a line starting with +, using .env syntax.
+// UpstreamChanged changes are followed by a comment naming the upstream variables
+func (d *EnvDiff) WriteText(w io.Writer) error {
+	for _, change := range d.Changes {
+		var lines []string
+		if change.Old != nil {
+			lines = append(lines, "-"+change.Name+"="+d.formatValue(change.Old))
+		}
+		if change.New != nil {
+			line := "+" + change.Name + "=" + d.formatValue(change.New)
+			if change.Kind == UpstreamChanged && len(change.Upstream) > 0 {
+				line += " # changed upstream: " + strings.Join(change.Upstream, ", ")
+			}
+			lines = append(lines, line)
+		}
+		for _, line := range lines {
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// formatValue returns the .env source text of a value, quoted as in the file for raw values
+func (d *EnvDiff) formatValue(v *ChangeValue) string {
+	if v.Masked {
+		return v.Value
+	}
+	raw, style := literalValue(v.Value)
+	if d.Raw {
+		// Keep the quote style of the file, unless the value can't be written with it
+		raw, style = v.Value, v.Quoted
+		if _, err := quoteValue(raw, style); err != nil {
+			raw, style = canonicalValue(v.Value, v.Quoted)
+		}
+	}
+	text, err := quoteValue(raw, style)
+	if err != nil {
+		return v.Value
+	}
+	return text
+}
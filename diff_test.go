@@ -0,0 +1,164 @@
+package dotenv_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/compose-spec/dotenv"
+	"gotest.tools/v3/assert"
+)
+
+func resolveString(t *testing.T, name, content string) *dotenv.ResolvedEnv {
+	t.Helper()
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader(content), dotenv.WithSourceName(name))
+	assert.NilError(t, err)
+	resolved, err := env.ResolveEnv(context.TODO(), nil)
+	assert.NilError(t, err)
+	return resolved
+}
+
+func TestDiff(t *testing.T) {
+	from := resolveString(t, "old.env", "HOST=localhost\nPORT=5432\nURL=postgres://${HOST}:${PORT}\nDEBUG=true\nDB_PASSWORD=hunter2\nNAME=api")
+	to := resolveString(t, "new.env", "HOST=db\nPORT=${DB_PORT:-5432}\nURL=postgres://${HOST}:${PORT}\nDB_PASSWORD='correct horse'\nNAME=api\nLOG_LEVEL=info")
+
+	diff := dotenv.Diff(from, to)
+	var text strings.Builder
+	assert.NilError(t, diff.WriteText(&text))
+	assert.Equal(t, text.String(), strings.Join([]string{
+		"-HOST=localhost",
+		"+HOST=db",
+		"-URL=postgres://localhost:5432",
+		"+URL=postgres://db:5432 # changed upstream: HOST",
+		"-DB_PASSWORD=hunter2",
+		"+DB_PASSWORD=correct horse",
+		"+LOG_LEVEL=info",
+		"-DEBUG=true",
+		"",
+	}, "\n"))
+	assert.Equal(t, diff.Changes[1].Kind, dotenv.UpstreamChanged)
+	assert.DeepEqual(t, diff.Changes[1].Upstream, []string{"HOST"})
+
+	text.Reset()
+	assert.NilError(t, dotenv.Diff(from, to, dotenv.WithRawValues()).WriteText(&text))
+	assert.Equal(t, text.String(), strings.Join([]string{
+		"-HOST=localhost",
+		"+HOST=db",
+		"-PORT=5432",
+		"+PORT=${DB_PORT:-5432}",
+		"-DB_PASSWORD=hunter2",
+		"+DB_PASSWORD='correct horse'",
+		"+LOG_LEVEL=info",
+		"-DEBUG=true",
+		"",
+	}, "\n"))
+
+	text.Reset()
+	assert.NilError(t, diff.Masked(dotenv.IsSecret).WriteText(&text))
+	assert.Assert(t, strings.Contains(text.String(), "-DB_PASSWORD=********\n+DB_PASSWORD=********\n"))
+	assert.Assert(t, !strings.Contains(text.String(), "hunter2"))
+	assert.Equal(t, diff.Changes[2].Old.Value, "hunter2")
+}
+
+func TestDiffJSON(t *testing.T) {
+	from := resolveString(t, "old.env", "TOKEN=abc\nREMOVED=1")
+	to := resolveString(t, "new.env", "TOKEN=def\nADDED=2")
+
+	out, err := json.Marshal(dotenv.Diff(from, to).Masked(dotenv.IsSecret))
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), `{"raw":false,"changes":[`+
+		`{"kind":"changed","name":"TOKEN","old":{"value":"********","location":"old.env:1","masked":true},"new":{"value":"********","location":"new.env:1","masked":true}},`+
+		`{"kind":"added","name":"ADDED","new":{"value":"2","location":"new.env:2"}},`+
+		`{"kind":"removed","name":"REMOVED","old":{"value":"1","location":"old.env:2"}}]}`)
+
+	var diff dotenv.EnvDiff
+	assert.NilError(t, json.Unmarshal(out, &diff))
+	assert.Equal(t, diff.Changes[1].Kind, dotenv.Added)
+}
+
+func TestDiffFiles(t *testing.T) {
+	from, err := dotenv.Parse(context.TODO(), strings.NewReader("A=1\nB=$A\nA=2"))
+	assert.NilError(t, err)
+	to, err := dotenv.Parse(context.TODO(), strings.NewReader("A=2\nB=${A}"))
+	assert.NilError(t, err)
+
+	diff := dotenv.DiffFiles(from, to)
+	assert.Equal(t, len(diff.Changes), 1)
+	assert.Equal(t, diff.Changes[0].Name, "B")
+	assert.Equal(t, diff.Changes[0].Kind, dotenv.Changed)
+	assert.Assert(t, diff.Raw)
+
+	// Raw values are written with the quotes of the files, so that literal values don't read as references
+	from, err = dotenv.Parse(context.TODO(), strings.NewReader("A='$HOME'\nB=\"$HOME\"\nC=\"it's\""))
+	assert.NilError(t, err)
+	to, err = dotenv.Parse(context.TODO(), strings.NewReader("A='$USER'\nB=\"$USER\"\nC=\"it is\""))
+	assert.NilError(t, err)
+	var text strings.Builder
+	assert.NilError(t, dotenv.DiffFiles(from, to).WriteText(&text))
+	assert.Equal(t, text.String(), strings.Join([]string{
+		"-A='$HOME'",
+		"+A='$USER'",
+		`-B="$HOME"`,
+		`+B="$USER"`,
+		`-C="it's"`,
+		`+C="it is"`,
+		"",
+	}, "\n"))
+}
+
+func TestIsSecret(t *testing.T) {
+	for name, secret := range map[string]bool{
+		"DB_PASSWORD":     true,
+		"API_KEY":         true,
+		"github.token":    true,
+		"AWS_SECRET_KEY":  true,
+		"MONKEY":          false,
+		"KEYBOARD_LAYOUT": false,
+		"HOST":            false,
+	} {
+		assert.Equal(t, dotenv.IsSecret(name), secret, name)
+	}
+}
+
+func TestDiffMaskedInterpolatedSecrets(t *testing.T) {
+	from := resolveString(t, "old.env", "DB_PASSWORD=hunter2\nDB_HOST=localhost\nDSN_BASE=u:${DB_PASSWORD}\nDATABASE_URL=postgres://u:${DB_PASSWORD}@${DB_HOST}\nREPLICA_URL=postgres://${DSN_BASE}@replica\nCOPY=hunter2")
+	to := resolveString(t, "new.env", "DB_PASSWORD=hunter3\nDB_HOST=db\nDSN_BASE=u:${DB_PASSWORD}\nDATABASE_URL=postgres://u:${DB_PASSWORD}@${DB_HOST}\nREPLICA_URL=postgres://${DSN_BASE}@replica\nCOPY=hunter3\nHOST=db")
+
+	var text strings.Builder
+	assert.NilError(t, dotenv.Diff(from, to).Masked(dotenv.IsSecret).WriteText(&text))
+	assert.Assert(t, !strings.Contains(text.String(), "hunter"), text.String())
+	assert.Equal(t, text.String(), strings.Join([]string{
+		"-DB_PASSWORD=********",
+		"+DB_PASSWORD=********",
+		"-DB_HOST=localhost",
+		"+DB_HOST=db",
+		"-DSN_BASE=********",
+		"+DSN_BASE=******** # changed upstream: DB_PASSWORD",
+		"-DATABASE_URL=********",
+		"+DATABASE_URL=******** # changed upstream: DB_HOST, DB_PASSWORD",
+		"-REPLICA_URL=********",
+		"+REPLICA_URL=******** # changed upstream: DSN_BASE",
+		"-COPY=********",
+		"+COPY=********",
+		"+HOST=db",
+		"",
+	}, "\n"))
+}
+
+func TestDiffExternalUpstream(t *testing.T) {
+	resolve := func(home string) *dotenv.ResolvedEnv {
+		env, err := dotenv.Parse(context.TODO(), strings.NewReader("CACHE=${HOME}/.cache\nNAME=api"))
+		assert.NilError(t, err)
+		resolved, err := env.ResolveEnv(context.TODO(), func(_ context.Context, name string) (dotenv.Variable, bool, error) {
+			return dotenv.Variable{Name: name, Value: home, Location: ":os"}, name == "HOME", nil
+		})
+		assert.NilError(t, err)
+		return resolved
+	}
+
+	diff := dotenv.Diff(resolve("/home/alice"), resolve("/home/bob"))
+	assert.Equal(t, len(diff.Changes), 1)
+	assert.Equal(t, diff.Changes[0].Kind, dotenv.UpstreamChanged)
+	assert.DeepEqual(t, diff.Changes[0].Upstream, []string{"HOME"})
+}
@@ -0,0 +1,363 @@
+package dotenv
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// EditOption configures how EnvFile edit methods write a value
+type EditOption func(*editOptions)
+
+type editOptions struct {
+	quote  *QuoteStyle
+	expand bool
+	export bool
+}
+
+// WithQuoteStyle forces the quote style used to write a value.
+// Edits fail if the quote style can't represent the value
+func WithQuoteStyle(style QuoteStyle) EditOption {
+	return func(o *editOptions) {
+		o.quote = &style
+	}
+}
+
+// WithExpansion writes the value so that $VARIABLE and ${VARIABLE} references it contains are expanded on Resolve.
+// By default, values are written so that they resolve literally
+func WithExpansion() EditOption {
+	return func(o *editOptions) {
+		o.expand = true
+	}
+}
+
+// WithExport adds an "export " prefix to newly added variables
+func WithExport() EditOption {
+	return func(o *editOptions) {
+		o.export = true
+	}
+}
+
+// entry pairs a syntax tree node with the variable it defines, if any
+type entry struct {
+	node     Node
+	variable *Variable
+}
+
+// Set returns a copy of the EnvFile assigning value to the variable name, keeping the layout of the file.
+// The last definition of the variable is updated where it is, or the variable is appended to the file if it isn't defined.
+// Unless a quote style is forced, the value is written using the simplest quoting which preserves it
+func (e *EnvFile) Set(name, value string, opts ...EditOption) (*EnvFile, error) {
+	entries, err := e.entries()
+	if err != nil {
+		return nil, err
+	}
+	if !isValidVariableName(name) {
+		return nil, fmt.Errorf("invalid variable name %q", name)
+	}
+
+	idx := lastDefinition(entries, name)
+	if idx == -1 {
+		return e.insert(entries, len(entries), name, value, opts)
+	}
+
+	var options editOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	text, raw, style, err := encodeValue(value, options)
+	if err != nil {
+		return nil, err
+	}
+
+	node := entries[idx].node
+	node.Value = text
+	if strings.Contains(node.Trailing, "#") && style != Unquoted {
+		// A quoted value can't be followed by a comment, move it above the variable
+		comment := entry{node: Node{Kind: CommentNode, Text: strings.TrimSpace(node.Trailing), EOL: e.newline()}}
+		node.Trailing = ""
+		entries = slices.Insert(entries, idx, comment)
+		idx++
+	}
+	entries[idx].node = node
+	entries[idx].variable.RawValue = raw
+	entries[idx].variable.Quoted = style
+	entries[idx].variable.Value = ""
+
+	return withEntries(entries), nil
+}
+
+// Unset returns a copy of the EnvFile without the definitions and exports of the variable name,
+// and whether it was defined. Comments surrounding the definitions are kept
+func (e *EnvFile) Unset(name string) (*EnvFile, bool, error) {
+	entries, err := e.entries()
+	if err != nil {
+		return nil, false, err
+	}
+
+	count := len(entries)
+	kept := slices.DeleteFunc(entries, func(en entry) bool {
+		return (en.node.Kind == VariableNode || en.node.Kind == ExportNode) && en.node.Name == name
+	})
+	return withEntries(kept), len(kept) != count, nil
+}
+
+// Rename returns a copy of the EnvFile renaming all definitions and exports of the variable oldName to newName.
+// References to the variable in other values are not rewritten
+func (e *EnvFile) Rename(oldName, newName string) (*EnvFile, error) {
+	entries, err := e.entries()
+	if err != nil {
+		return nil, err
+	}
+	if !isValidVariableName(newName) {
+		return nil, fmt.Errorf("invalid variable name %q", newName)
+	}
+	if lastDefinition(entries, oldName) == -1 {
+		return nil, fmt.Errorf("variable %q is not defined", oldName)
+	}
+	if lastDefinition(entries, newName) != -1 {
+		return nil, fmt.Errorf("variable %q is already defined", newName)
+	}
+
+	for i := range entries {
+		node := &entries[i].node
+		if node.Name != oldName {
+			continue
+		}
+		switch node.Kind {
+		case VariableNode:
+			node.Name = newName
+			entries[i].variable.Name = newName
+		case ExportNode:
+			node.Name = newName
+			node.Text = "export " + strings.Replace(node.Text[len("export "):], oldName, newName, 1)
+		}
+	}
+
+	return withEntries(entries), nil
+}
+
+// InsertAfter returns a copy of the EnvFile adding the variable name right after the last definition
+// of the variable anchor
+func (e *EnvFile) InsertAfter(anchor, name, value string, opts ...EditOption) (*EnvFile, error) {
+	entries, err := e.entries()
+	if err != nil {
+		return nil, err
+	}
+	if !isValidVariableName(name) {
+		return nil, fmt.Errorf("invalid variable name %q", name)
+	}
+	if lastDefinition(entries, name) != -1 {
+		return nil, fmt.Errorf("variable %q is already defined", name)
+	}
+	idx := lastDefinition(entries, anchor)
+	if idx == -1 {
+		return nil, fmt.Errorf("variable %q is not defined", anchor)
+	}
+
+	return e.insert(entries, idx+1, name, value, opts)
+}
+
+// MoveAfter returns a copy of the EnvFile moving the last definition of the variable name right after
+// the last definition of the variable anchor.
+// Comment lines directly above the definition and exports directly following it are moved along
+func (e *EnvFile) MoveAfter(name, anchor string) (*EnvFile, error) {
+	entries, err := e.entries()
+	if err != nil {
+		return nil, err
+	}
+	idx := lastDefinition(entries, name)
+	if idx == -1 {
+		return nil, fmt.Errorf("variable %q is not defined", name)
+	}
+	if lastDefinition(entries, anchor) == -1 {
+		return nil, fmt.Errorf("variable %q is not defined", anchor)
+	}
+	if name == anchor {
+		return withEntries(entries), nil
+	}
+
+	// Make sure every entry ends with a line break while moving them around
+	ending := entries[len(entries)-1].node.EOL
+	if ending == "" {
+		entries[len(entries)-1].node.EOL = e.newline()
+	}
+
+	start, end := idx, idx+1
+	for start > 0 && entries[start-1].node.Kind == CommentNode {
+		start--
+	}
+	for end < len(entries) && entries[end].node.Kind == ExportNode && entries[end].node.Name == name {
+		end++
+	}
+	moved := slices.Clone(entries[start:end])
+	entries = slices.Delete(entries, start, end)
+
+	target := lastDefinition(entries, anchor) + 1
+	for target < len(entries) && entries[target].node.Kind == ExportNode && entries[target].node.Name == anchor {
+		target++
+	}
+	entries = slices.Insert(entries, target, moved...)
+	if ending == "" {
+		entries[len(entries)-1].node.EOL = ""
+	}
+
+	return withEntries(entries), nil
+}
+
+// insert returns an EnvFile adding a new definition of the variable name at index idx of entries
+func (e *EnvFile) insert(entries []entry, idx int, name, value string, opts []EditOption) (*EnvFile, error) {
+	var options editOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	text, raw, style, err := encodeValue(value, options)
+	if err != nil {
+		return nil, err
+	}
+
+	node := Node{
+		Kind:      VariableNode,
+		Name:      name,
+		Separator: "=",
+		Value:     text,
+		EOL:       e.newline(),
+	}
+	if options.export {
+		node.Export = "export "
+	}
+	if idx == len(entries) && idx > 0 && entries[idx-1].node.EOL == "" {
+		// Appending to a file which doesn't end with a line break
+		entries[idx-1].node.EOL = node.EOL
+		node.EOL = ""
+	}
+	variable := Variable{
+		Name:     name,
+		RawValue: raw,
+		Quoted:   style,
+		Expanded: make(map[string]Location),
+	}
+
+	return withEntries(slices.Insert(entries, idx, entry{node: node, variable: &variable})), nil
+}
+
+// encodeValue returns the source text, raw value and quote style used to write value
+func encodeValue(value string, options editOptions) (string, string, QuoteStyle, error) {
+	raw, style := literalValue(value)
+	if options.expand {
+		raw, style = value, expandableValue(value)
+	}
+	if options.quote != nil && *options.quote != style {
+		style = *options.quote
+		raw = value
+		if style != Quoted && !options.expand {
+			raw = escapeDollar(value)
+		}
+	}
+
+	text, err := quoteValue(raw, style)
+	if err != nil {
+		return "", "", 0, err
+	}
+	return text, raw, style, nil
+}
+
+// entries pairs the nodes of the syntax tree with the variables they define.
+// A syntax tree is built from the variables of an EnvFile which has none
+func (e *EnvFile) entries() ([]entry, error) {
+	if len(e.Nodes) == 0 {
+		entries := make([]entry, 0, len(e.Variables))
+		for i := range e.Variables {
+			variable := e.Variables[i]
+			node, err := variableNode(variable)
+			if err != nil {
+				return nil, err
+			}
+			// Keep the variable consistent with the node when it had to be written differently
+			variable.RawValue, variable.Quoted = unquote(node.Value)
+			entries = append(entries, entry{node: node, variable: &variable})
+		}
+		return entries, nil
+	}
+
+	entries := make([]entry, 0, len(e.Nodes))
+	next := 0
+	for _, node := range e.Nodes {
+		en := entry{node: node}
+		if node.Kind == VariableNode {
+			if next >= len(e.Variables) || e.Variables[next].Name != node.Name {
+				return nil, fmt.Errorf("syntax tree is out of sync with variables")
+			}
+			variable := e.Variables[next]
+			en.variable = &variable
+			next++
+		}
+		entries = append(entries, en)
+	}
+	if next != len(e.Variables) {
+		return nil, fmt.Errorf("syntax tree is out of sync with variables")
+	}
+	return entries, nil
+}
+
+// withEntries returns a new EnvFile with the syntax tree and variables of entries
+func withEntries(entries []entry) *EnvFile {
+	e := &EnvFile{
+		Nodes:     make([]Node, 0, len(entries)),
+		Variables: make([]Variable, 0, len(entries)),
+	}
+	for _, en := range entries {
+		e.Nodes = append(e.Nodes, en.node)
+		if en.variable != nil {
+			e.Variables = append(e.Variables, *en.variable)
+		}
+	}
+	return e
+}
+
+// newline returns the line terminator used by the file
+func (e *EnvFile) newline() string {
+	for _, node := range e.Nodes {
+		if node.EOL == "\n" || node.EOL == "\r\n" {
+			return node.EOL
+		}
+	}
+	return "\n"
+}
+
+// variableNode builds the syntax tree node of a variable.
+// Variables with a quote style unable to represent their raw value are written double-quoted
+func variableNode(v Variable) (Node, error) {
+	if !isValidVariableName(v.Name) {
+		return Node{}, fmt.Errorf("invalid variable name %q", v.Name)
+	}
+
+	raw, style := v.RawValue, v.Quoted
+	switch {
+	case style == Unquoted && !canBeUnquoted(raw):
+		style = DoubleQuoted
+	case style == Quoted && !canBeSingleQuoted(raw):
+		raw, style = escapeDollar(raw), DoubleQuoted
+	}
+	text, err := quoteValue(raw, style)
+	if err != nil {
+		return Node{}, err
+	}
+	return Node{
+		Kind:      VariableNode,
+		Name:      v.Name,
+		Separator: "=",
+		Value:     text,
+		EOL:       "\n",
+	}, nil
+}
+
+// lastDefinition returns the index of the last entry defining the variable name, or -1
+func lastDefinition(entries []entry, name string) int {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].node.Kind == VariableNode && entries[i].node.Name == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,270 @@
+package dotenv_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/compose-spec/dotenv"
+	"gotest.tools/v3/assert"
+)
+
+func TestEdit(t *testing.T) {
+	type test struct {
+		name   string
+		input  string
+		edit   func(env *dotenv.EnvFile) (*dotenv.EnvFile, error)
+		output string
+		expect map[string]string
+		err    string
+	}
+	tests := []test{
+		{
+			name:  "set existing variable keeps layout",
+			input: "# database\nexport DB_HOST : db # host\nDB_PORT=5432\n",
+			edit: func(env *dotenv.EnvFile) (*dotenv.EnvFile, error) {
+				return env.Set("DB_HOST", "postgres")
+			},
+			output: "# database\nexport DB_HOST : postgres # host\nDB_PORT=5432\n",
+			expect: map[string]string{"DB_HOST": "postgres", "DB_PORT": "5432"},
+		},
+		{
+			name:  "set last definition",
+			input: "FOO=a\nBAR=$FOO\nFOO=b\n",
+			edit: func(env *dotenv.EnvFile) (*dotenv.EnvFile, error) {
+				return env.Set("FOO", "c")
+			},
+			output: "FOO=a\nBAR=$FOO\nFOO=c\n",
+			expect: map[string]string{"FOO": "c", "BAR": "a"},
+		},
+		{
+			name:  "set new variable",
+			input: "FOO=a\n",
+			edit: func(env *dotenv.EnvFile) (*dotenv.EnvFile, error) {
+				return env.Set("BAR", "b", dotenv.WithExport())
+			},
+			output: "FOO=a\nexport BAR=b\n",
+			expect: map[string]string{"FOO": "a", "BAR": "b"},
+		},
+		{
+			name:  "set new variable without final line break",
+			input: "FOO=a\r\nBAZ=c",
+			edit: func(env *dotenv.EnvFile) (*dotenv.EnvFile, error) {
+				return env.Set("BAR", "b")
+			},
+			output: "FOO=a\r\nBAZ=c\r\nBAR=b",
+			expect: map[string]string{"FOO": "a", "BAR": "b", "BAZ": "c"},
+		},
+		{
+			name:  "set value with comment marker",
+			input: "FOO=a\n",
+			edit: func(env *dotenv.EnvFile) (*dotenv.EnvFile, error) {
+				return env.Set("FOO", "a # b")
+			},
+			output: "FOO='a # b'\n",
+			expect: map[string]string{"FOO": "a # b"},
+		},
+		{
+			name:  "set value with dollar sign",
+			input: "BAR=bar\nFOO=a\n",
+			edit: func(env *dotenv.EnvFile) (*dotenv.EnvFile, error) {
+				return env.Set("FOO", "$BAR")
+			},
+			output: "BAR=bar\nFOO='$BAR'\n",
+			expect: map[string]string{"BAR": "bar", "FOO": "$BAR"},
+		},
+		{
+			name:  "set value with expansion",
+			input: "BAR=bar\nFOO=a\n",
+			edit: func(env *dotenv.EnvFile) (*dotenv.EnvFile, error) {
+				return env.Set("FOO", "${BAR}:5432", dotenv.WithExpansion())
+			},
+			output: "BAR=bar\nFOO=${BAR}:5432\n",
+			expect: map[string]string{"BAR": "bar", "FOO": "bar:5432"},
+		},
+		{
+			name:  "set value with quotes, dollar sign and newlines",
+			input: "FOO=\"multi\nline\"\nBAR=b\n",
+			edit: func(env *dotenv.EnvFile) (*dotenv.EnvFile, error) {
+				return env.Set("FOO", "it's \"$HOME\"\nC:\\")
+			},
+			output: "FOO=\"it's \\\"\\$HOME\\\"\\nC:\\\\\"\nBAR=b\n",
+			expect: map[string]string{"FOO": "it's \"$HOME\"\nC:\\", "BAR": "b"},
+		},
+		{
+			name:  "set quoted value moves inline comment above",
+			input: "FOO=a # keep me\n",
+			edit: func(env *dotenv.EnvFile) (*dotenv.EnvFile, error) {
+				return env.Set("FOO", " padded ")
+			},
+			output: "# keep me\nFOO=' padded '\n",
+			expect: map[string]string{"FOO": " padded "},
+		},
+		{
+			name:  "set with forced quote style",
+			input: "FOO=a\n",
+			edit: func(env *dotenv.EnvFile) (*dotenv.EnvFile, error) {
+				return env.Set("FOO", "b", dotenv.WithQuoteStyle(dotenv.DoubleQuoted))
+			},
+			output: "FOO=\"b\"\n",
+			expect: map[string]string{"FOO": "b"},
+		},
+		{
+			name:  "set with unsafe forced quote style",
+			input: "FOO=a\n",
+			edit: func(env *dotenv.EnvFile) (*dotenv.EnvFile, error) {
+				return env.Set("FOO", "b # c", dotenv.WithQuoteStyle(dotenv.Unquoted))
+			},
+			err: "value \"b # c\" can't be written unquoted",
+		},
+		{
+			name:  "set invalid name",
+			input: "FOO=a\n",
+			edit: func(env *dotenv.EnvFile) (*dotenv.EnvFile, error) {
+				return env.Set("1FOO", "b")
+			},
+			err: "invalid variable name \"1FOO\"",
+		},
+		{
+			name:  "unset removes definitions and exports",
+			input: "# foo\nFOO=a\nBAR=b\nexport FOO\nFOO=c\n",
+			edit: func(env *dotenv.EnvFile) (*dotenv.EnvFile, error) {
+				env, defined, err := env.Unset("FOO")
+				assert.NilError(t, err)
+				assert.Assert(t, defined)
+				env, defined, err = env.Unset("UNDEFINED")
+				assert.NilError(t, err)
+				assert.Assert(t, !defined)
+				return env, nil
+			},
+			output: "# foo\nBAR=b\n",
+			expect: map[string]string{"BAR": "b"},
+		},
+		{
+			name:  "rename",
+			input: "FOO = a # comment\nexport FOO\nBAR=$FOO\n",
+			edit: func(env *dotenv.EnvFile) (*dotenv.EnvFile, error) {
+				return env.Rename("FOO", "BAZ")
+			},
+			output: "BAZ = a # comment\nexport BAZ\nBAR=$FOO\n",
+			expect: map[string]string{"BAZ": "a", "BAR": ""},
+		},
+		{
+			name:  "rename to existing variable",
+			input: "FOO=a\nBAR=b\n",
+			edit: func(env *dotenv.EnvFile) (*dotenv.EnvFile, error) {
+				return env.Rename("FOO", "BAR")
+			},
+			err: "variable \"BAR\" is already defined",
+		},
+		{
+			name:  "rename undefined variable",
+			input: "FOO=a\n",
+			edit: func(env *dotenv.EnvFile) (*dotenv.EnvFile, error) {
+				return env.Rename("BAR", "BAZ")
+			},
+			err: "variable \"BAR\" is not defined",
+		},
+		{
+			name:  "insert after",
+			input: "FOO=a\nexport FOO\n\nBAR=b\n",
+			edit: func(env *dotenv.EnvFile) (*dotenv.EnvFile, error) {
+				return env.InsertAfter("FOO", "BAZ", "${FOO}-c", dotenv.WithExpansion())
+			},
+			output: "FOO=a\nBAZ=${FOO}-c\nexport FOO\n\nBAR=b\n",
+			expect: map[string]string{"FOO": "a", "BAR": "b", "BAZ": "a-c"},
+		},
+		{
+			name:  "insert after undefined anchor",
+			input: "FOO=a\n",
+			edit: func(env *dotenv.EnvFile) (*dotenv.EnvFile, error) {
+				return env.InsertAfter("BAR", "BAZ", "c")
+			},
+			err: "variable \"BAR\" is not defined",
+		},
+		{
+			name:  "move after with comments and exports",
+			input: "# about foo\nFOO=a\nexport FOO\nBAR=b\nexport BAR\nBAZ=c",
+			edit: func(env *dotenv.EnvFile) (*dotenv.EnvFile, error) {
+				return env.MoveAfter("FOO", "BAR")
+			},
+			output: "BAR=b\nexport BAR\n# about foo\nFOO=a\nexport FOO\nBAZ=c",
+			expect: map[string]string{"FOO": "a", "BAR": "b", "BAZ": "c"},
+		},
+		{
+			name:  "move after last variable",
+			input: "FOO=a\nBAR=b",
+			edit: func(env *dotenv.EnvFile) (*dotenv.EnvFile, error) {
+				return env.MoveAfter("FOO", "BAR")
+			},
+			output: "BAR=b\nFOO=a",
+			expect: map[string]string{"FOO": "a", "BAR": "b"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			original, err := dotenv.Parse(context.TODO(), strings.NewReader(test.input))
+			assert.NilError(t, err)
+
+			env, err := test.edit(original)
+			if test.err != "" {
+				assert.Error(t, err, test.err)
+				return
+			}
+			assert.NilError(t, err)
+
+			// Edits leave the original EnvFile unchanged
+			var out strings.Builder
+			_, err = original.WriteTo(&out)
+			assert.NilError(t, err)
+			assert.Equal(t, out.String(), test.input)
+
+			out.Reset()
+			_, err = env.WriteTo(&out)
+			assert.NilError(t, err)
+			assert.Equal(t, out.String(), test.output)
+
+			vars, err := env.Resolve(nil)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, test.expect, vars)
+
+			// The edited file must parse back to the same variables
+			reparsed, err := dotenv.Parse(context.TODO(), strings.NewReader(out.String()))
+			assert.NilError(t, err)
+			vars, err = reparsed.Resolve(nil)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, test.expect, vars)
+		})
+	}
+}
+
+func TestEditWithoutSyntaxTree(t *testing.T) {
+	env := &dotenv.EnvFile{
+		Variables: []dotenv.Variable{
+			{Name: "FOO", RawValue: "a b", Quoted: dotenv.Unquoted},
+			{Name: "BAR", RawValue: "it's $FOO", Quoted: dotenv.Quoted},
+		},
+	}
+	env, err := env.Set("BAZ", "c")
+	assert.NilError(t, err)
+
+	var out strings.Builder
+	_, err = env.WriteTo(&out)
+	assert.NilError(t, err)
+	assert.Equal(t, out.String(), "FOO=a b\nBAR=\"it's \\$FOO\"\nBAZ=c\n")
+
+	vars, err := env.Resolve(nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[string]string{"FOO": "a b", "BAR": "it's $FOO", "BAZ": "c"}, vars)
+}
+
+func TestUnsetOutOfSyncSyntaxTree(t *testing.T) {
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader("FOO=a\nBAR=b\n"))
+	assert.NilError(t, err)
+	env.Variables = env.Variables[:1]
+
+	_, defined, err := env.Unset("FOO")
+	assert.Error(t, err, "syntax tree is out of sync with variables")
+	assert.Assert(t, !defined)
+}
@@ -1,7 +1,9 @@
 package dotenv
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"slices"
 	"strings"
 )
 
@@ -17,72 +19,201 @@ const (
 	DoubleQuoted
 )
 
-// EnvFile represents a parsed .env file containing a list of variables
+// EnvFile represents a parsed .env file containing a list of variables.
+// An EnvFile is immutable: resolving or writing it doesn't modify it, and edits return a modified copy,
+// so it can be shared across goroutines without synchronization as long as Variables and Nodes aren't modified directly
 type EnvFile struct {
 	Variables []Variable
-	expanded  bool
+	// Nodes is the lossless syntax tree of the file, recording comments, blank lines and layout.
+	// VariableNode entries match Variables in order
+	Nodes []Node
+}
+
+// ResolveOption configures variable expansion performed by Resolve
+type ResolveOption func(*resolveOptions)
+
+type resolveOptions struct {
+	dependencyOrder bool
+	strict          bool
+	dialect         Dialect
+	filters         map[string]Filter
+	runner          CommandRunner
+	overrides       []Variable
+}
+
+// Dialect selects the parameter expansion syntax supported by Resolve
+type Dialect int
+
+const (
+	// Compose supports the ${VAR-default}, ${VAR+replacement} and ${VAR?error} operators of the Compose specification
+	Compose Dialect = iota
+	// Bash adds the string operations of bash to Compose: ${#VAR}, ${VAR:offset:length}, ${VAR#pattern},
+	// ${VAR##pattern}, ${VAR%pattern}, ${VAR%%pattern}, ${VAR/pattern/replacement}, ${VAR//pattern/replacement},
+	// ${VAR^^} and ${VAR,,}. As # starts a comment in unquoted values, operations using # must be double-quoted
+	Bash
+)
+
+// WithDialect selects the parameter expansion syntax, Compose by default
+func WithDialect(dialect Dialect) ResolveOption {
+	return func(o *resolveOptions) {
+		o.dialect = dialect
+	}
+}
+
+// WithDependencyOrder resolves variables in the order of their dependencies instead of top to bottom,
+// so that a variable can reference variables defined further down the file.
+// A reference to the variable being defined, like PATH=$PATH:/bin, refers to its previous definition if any,
+// or to the external lookup. Circular references are reported as a *CycleError
+func WithDependencyOrder() ResolveOption {
+	return func(o *resolveOptions) {
+		o.dependencyOrder = true
+	}
+}
+
+// WithStrict fails to resolve variables referencing undefined variables, instead of expanding them to an empty string.
+// All undefined references are reported as *ExpansionError of kind UndefinedVariable, joined in the returned error.
+// References with a default or replacement value, like ${VAR:-default} or ${VAR-default}, are allowed to be undefined
+func WithStrict() ResolveOption {
+	return func(o *resolveOptions) {
+		o.strict = true
+	}
+}
+
+// WithOverrides gives the variables precedence over the definitions of the file, like variables set in the shell
+// override .env files in Compose: a definition of an overridden variable resolves to the value of the override,
+// with its Location, and references to the variable expand to that value.
+// Overriding variables the file doesn't define are added to the resolved variables
+func WithOverrides(variables ...Variable) ResolveOption {
+	return func(o *resolveOptions) {
+		o.overrides = append(o.overrides, variables...)
+	}
 }
 
 // Resolve performs variable expansion and returns the environment variables as a map[string]string
-// An optional lookup function can be provided for resolving variables not defined in the file
-func (e *EnvFile) Resolve(externalLookup LookupFn) (map[string]string, error) {
-	if !e.expanded {
-		if err := e.expand(externalLookup); err != nil {
-			return nil, err
+// An optional lookup function can be provided for resolving variables not defined in the file.
+// The EnvFile is left untouched, use ResolveEnv to get the resolved Variables
+func (e *EnvFile) Resolve(externalLookup LookupFn, opts ...ResolveOption) (map[string]string, error) {
+	return e.ResolveContext(context.Background(), ContextLookup(externalLookup), opts...)
+}
+
+// ResolveContext is like Resolve, with a context-aware lookup function. Resolution stops with the context error
+// once ctx is done. Errors of the lookup function are reported as *ExpansionError of kind LookupFailed
+// wrapping the error, and never fall back to default values
+func (e *EnvFile) ResolveContext(ctx context.Context, externalLookup ContextLookupFn, opts ...ResolveOption) (map[string]string, error) {
+	resolved, err := e.ResolveEnv(ctx, externalLookup, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return resolved.Map(), nil
+}
+
+// ResolveEnv is like ResolveContext, returning the resolved variables along with their provenance.
+// The EnvFile is left untouched, so that it can be resolved many times, e.g. with the lookups of
+// different environments
+func (e *EnvFile) ResolveEnv(ctx context.Context, externalLookup ContextLookupFn, opts ...ResolveOption) (*ResolvedEnv, error) {
+	var options resolveOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if externalLookup != nil {
+		externalLookup = reportLookupErrors(externalLookup)
+	}
+
+	expand := e.expand
+	if options.dependencyOrder {
+		expand = e.expandInDependencyOrder
+	}
+	variables := slices.Clone(e.Variables)
+	overrides := make(map[string]Variable, len(options.overrides))
+	for _, v := range options.overrides {
+		// Overrides are literal values
+		overrides[v.Name] = Variable{Name: v.Name, Value: v.Value, RawValue: v.Value, Quoted: Quoted, Location: v.Location, Expanded: make(map[string]Location)}
+	}
+	for i, v := range variables {
+		if override, ok := overrides[v.Name]; ok {
+			variables[i] = override
+		}
+	}
+	x := &expander{ctx: ctx, options: options}
+	if err := expand(x, variables, externalLookup); err != nil {
+		return nil, err
+	}
+	if len(x.undefined) > 0 {
+		errs := make([]error, len(x.undefined))
+		for i, err := range x.undefined {
+			errs[i] = err
 		}
-		e.expanded = true
+		return nil, errors.Join(errs...)
 	}
 
-	result := make(map[string]string, len(e.Variables))
-	for _, variable := range e.Variables {
-		result[variable.Name] = variable.Value
+	resolved := newResolvedEnv()
+	for _, v := range variables {
+		resolved.set(v)
+	}
+	for _, v := range options.overrides {
+		if _, ok := resolved.index[v.Name]; !ok {
+			resolved.set(overrides[v.Name])
+		}
+	}
+	return resolved, nil
+}
+
+// reportLookupErrors reports the errors of the lookup function as *ExpansionError of kind LookupFailed,
+// so that they are not mistaken for unset variables
+func reportLookupErrors(lookup ContextLookupFn) ContextLookupFn {
+	return func(ctx context.Context, name string) (Variable, bool, error) {
+		v, ok, err := lookup(ctx, name)
+		if err != nil {
+			return Variable{}, false, &ExpansionError{Kind: LookupFailed, Reference: name, Err: err}
+		}
+		return v, ok, nil
 	}
-	return result, nil
 }
 
-// expand processes variable expansion in the EnvFile
+// expand processes variable expansion of variables, a copy of the variables of the EnvFile
 // It replaces $VARIABLE and ${VARIABLE} references with values from previously declared variables
 // and optionally from an additional lookup function
-func (e *EnvFile) expand(externalLookup LookupFn) error {
+func (e *EnvFile) expand(x *expander, variables []Variable, externalLookup ContextLookupFn) error {
 	// Build a map of variables as we go for lookups
 	vars := make(map[string]Variable)
 
-	for i := range e.Variables {
+	for i := range variables {
 		// Skip expansion for single-quoted variables
-		if e.Variables[i].Quoted == Quoted {
+		if variables[i].Quoted == Quoted {
 			// For single-quoted variables, just copy RawValue to Value
-			e.Variables[i].Value = e.Variables[i].RawValue
-			vars[e.Variables[i].Name] = e.Variables[i]
+			variables[i].Value = variables[i].RawValue
+			vars[variables[i].Name] = variables[i]
 			continue
 		}
 
 		// Create a composite lookup that checks internal vars first, then external lookup
-		internalLookup := func(name string) (Variable, bool) {
+		internalLookup := func(_ context.Context, name string) (Variable, bool, error) {
 			v, ok := vars[name]
-			return v, ok
+			return v, ok, nil
 		}
 
-		var lookup LookupFn
+		var lookup ContextLookupFn
 		if externalLookup != nil {
 			// Create composite with internal lookup having highest priority
-			lookup = func(name string) (Variable, bool) {
+			lookup = func(ctx context.Context, name string) (Variable, bool, error) {
 				// Try internal lookup first
-				if v, ok := internalLookup(name); ok {
-					return v, true
+				if v, ok, _ := internalLookup(ctx, name); ok {
+					return v, true, nil
 				}
 				// Try external lookup
-				return externalLookup(name)
+				return externalLookup(ctx, name)
 			}
 		} else {
 			lookup = internalLookup
 		}
 
-		if err := e.Variables[i].expandValue(lookup); err != nil {
+		if err := variables[i].expandValue(x, lookup); err != nil {
 			return err
 		}
 
 		// Add the current variable to the map for future expansions
-		vars[e.Variables[i].Name] = e.Variables[i]
+		vars[variables[i].Name] = variables[i]
 	}
 	return nil
 }
@@ -104,6 +235,67 @@ func findClosingBrace(value string, start int) int {
 	return -1 // no matching closing brace found
 }
 
+// findClosingParen finds the index of the closing parenthesis that matches the opening parenthesis
+// at the given start position, accounting for nested parentheses. As in shell commands, parentheses escaped
+// by a backslash or in quoted strings and ${...} references don't count
+func findClosingParen(value string, start int) int {
+	depth := 1
+	for i := start; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '\'':
+			end := strings.IndexByte(value[i+1:], '\'')
+			if end == -1 {
+				return -1
+			}
+			i += end + 1
+		case '"':
+			if i = findClosingQuote(value, i+1); i == -1 {
+				return -1
+			}
+		case '$':
+			if i+1 < len(value) && value[i+1] == '{' {
+				if i = findClosingBrace(value, i+2); i == -1 {
+					return -1
+				}
+			}
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1 // no matching closing parenthesis found
+}
+
+// findClosingQuote finds the index of the double quote ending the shell string starting at the given position,
+// skipping escaped characters, ${...} references and $(...) commands
+func findClosingQuote(value string, start int) int {
+	for i := start; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		case '$':
+			if i+1 < len(value) && value[i+1] == '{' {
+				if i = findClosingBrace(value, i+2); i == -1 {
+					return -1
+				}
+			} else if i+1 < len(value) && value[i+1] == '(' {
+				if i = findClosingParen(value, i+2); i == -1 {
+					return -1
+				}
+			}
+		}
+	}
+	return -1 // no closing quote found
+}
+
 // findOperator finds the first occurrence of the operator at the top level (not inside nested braces)
 func findOperator(content string, operator string) int {
 	depth := 0
@@ -123,8 +315,48 @@ func findOperator(content string, operator string) int {
 	return -1
 }
 
+// expander expands variable references according to the resolve options
+type expander struct {
+	ctx     context.Context
+	options resolveOptions
+	// undefined collects the references to undefined variables found in strict mode
+	undefined []*ExpansionError
+}
+
+// undefinedReference records a reference to an undefined variable, to be reported in strict mode
+func (x *expander) undefinedReference(name string) {
+	if !x.options.strict {
+		return
+	}
+	// Report each undefined variable once per value, Variable is set once the value is expanded
+	for _, err := range x.undefined {
+		if err.Variable == "" && err.Reference == name {
+			return
+		}
+	}
+	x.undefined = append(x.undefined, &ExpansionError{Kind: UndefinedVariable, Reference: name})
+}
+
+// findPipes finds the indexes of the | separating the filters of a ${VAR|filter} pipeline,
+// at the top level (not inside nested braces)
+func findPipes(content string) []int {
+	var pipes []int
+	depth := 0
+	for i := 0; i < len(content); i++ {
+		if content[i] == '$' && i+1 < len(content) && content[i+1] == '{' {
+			depth++
+			i++ // skip the '{'
+		} else if content[i] == '}' && depth > 0 {
+			depth--
+		} else if depth == 0 && content[i] == '|' {
+			pipes = append(pipes, i)
+		}
+	}
+	return pipes
+}
+
 // expandString expands variable references in a string value
-func expandString(value string, lookup LookupFn) (string, map[string]Location, error) {
+func (x *expander) expandString(value string, lookup ContextLookupFn) (string, map[string]Location, error) {
 	expanded := make(map[string]Location)
 	var result strings.Builder
 	result.Grow(len(value))
@@ -146,16 +378,30 @@ func expandString(value string, lookup LookupFn) (string, map[string]Location, e
 				if endIdx != -1 {
 					content := value[i+2 : endIdx]
 
-					// Check for ${VAR:?error} (error if unset or empty)
-					if colonQuestionIdx := findOperator(content, ":?"); colonQuestionIdx != -1 {
+					if name, calls, ok := parsePipeline(content); ok {
+						// Filter pipeline, like ${VAR|lower}
+						expandedPipeline, err := x.expandPipeline(name, calls, lookup, expanded)
+						if err != nil {
+							return "", nil, err
+						}
+						result.WriteString(expandedPipeline)
+					} else if op, ok := x.bashOperation(content); ok {
+						// Bash string operation, like ${VAR#pattern}
+						expandedOp, err := x.expandBashOperation(op, lookup, expanded)
+						if err != nil {
+							return "", nil, err
+						}
+						result.WriteString(expandedOp)
+					} else if colonQuestionIdx := findOperator(content, ":?"); colonQuestionIdx != -1 {
+						// Check for ${VAR:?error} (error if unset or empty)
 						varName := content[:colonQuestionIdx]
 						errorMsg := content[colonQuestionIdx+2:]
-						variable, ok := lookup(varName)
+						variable, ok, err := lookup(x.ctx, varName)
+						if err != nil {
+							return "", nil, err
+						}
 						if !ok || variable.Value == "" {
-							if errorMsg == "" {
-								return "", nil, fmt.Errorf("%s: required variable is not set", varName)
-							}
-							return "", nil, fmt.Errorf("%s", errorMsg)
+							return "", nil, &ExpansionError{Kind: RequiredVariableUnset, Reference: varName, Message: errorMsg}
 						}
 						result.WriteString(variable.Value)
 						expanded[varName] = variable.Location
@@ -163,12 +409,16 @@ func expandString(value string, lookup LookupFn) (string, map[string]Location, e
 						// Check for ${VAR:-default} (use default if unset or empty)
 						varName := content[:colonDashIdx]
 						defaultValue := content[colonDashIdx+2:]
-						if variable, ok := lookup(varName); ok && variable.Value != "" {
+						variable, ok, err := lookup(x.ctx, varName)
+						if err != nil {
+							return "", nil, err
+						}
+						if ok && variable.Value != "" {
 							result.WriteString(variable.Value)
 							expanded[varName] = variable.Location
 						} else {
 							// Recursively expand the default value
-							expandedDefault, nestedExpanded, err := expandString(defaultValue, lookup)
+							expandedDefault, nestedExpanded, err := x.expandString(defaultValue, lookup)
 							if err != nil {
 								return "", nil, err
 							}
@@ -182,9 +432,13 @@ func expandString(value string, lookup LookupFn) (string, map[string]Location, e
 						// Check for ${VAR:+replacement} (use replacement if set and non-empty)
 						varName := content[:colonPlusIdx]
 						replacement := content[colonPlusIdx+2:]
-						if variable, ok := lookup(varName); ok && variable.Value != "" {
+						variable, ok, err := lookup(x.ctx, varName)
+						if err != nil {
+							return "", nil, err
+						}
+						if ok && variable.Value != "" {
 							// Recursively expand the replacement value
-							expandedReplacement, nestedExpanded, err := expandString(replacement, lookup)
+							expandedReplacement, nestedExpanded, err := x.expandString(replacement, lookup)
 							if err != nil {
 								return "", nil, err
 							}
@@ -200,25 +454,29 @@ func expandString(value string, lookup LookupFn) (string, map[string]Location, e
 						// Check for ${VAR?error} (error if unset, but can be empty)
 						varName := content[:questionIdx]
 						errorMsg := content[questionIdx+1:]
-						if variable, ok := lookup(varName); !ok {
-							if errorMsg == "" {
-								return "", nil, fmt.Errorf("%s: required variable is not set", varName)
-							}
-							return "", nil, fmt.Errorf("%s", errorMsg)
-						} else {
-							result.WriteString(variable.Value)
-							expanded[varName] = variable.Location
+						variable, ok, err := lookup(x.ctx, varName)
+						if err != nil {
+							return "", nil, err
+						}
+						if !ok {
+							return "", nil, &ExpansionError{Kind: RequiredVariableUnset, Reference: varName, Message: errorMsg}
 						}
+						result.WriteString(variable.Value)
+						expanded[varName] = variable.Location
 					} else if dashIdx := findOperator(content, "-"); dashIdx != -1 {
 						// Check for ${VAR-default} (use default if unset)
 						varName := content[:dashIdx]
 						defaultValue := content[dashIdx+1:]
-						if variable, ok := lookup(varName); ok {
+						variable, ok, err := lookup(x.ctx, varName)
+						if err != nil {
+							return "", nil, err
+						}
+						if ok {
 							result.WriteString(variable.Value)
 							expanded[varName] = variable.Location
 						} else {
 							// Recursively expand the default value
-							expandedDefault, nestedExpanded, err := expandString(defaultValue, lookup)
+							expandedDefault, nestedExpanded, err := x.expandString(defaultValue, lookup)
 							if err != nil {
 								return "", nil, err
 							}
@@ -232,9 +490,13 @@ func expandString(value string, lookup LookupFn) (string, map[string]Location, e
 						// Check for ${VAR+replacement} (use replacement if set)
 						varName := content[:plusIdx]
 						replacement := content[plusIdx+1:]
-						if variable, ok := lookup(varName); ok {
+						variable, ok, err := lookup(x.ctx, varName)
+						if err != nil {
+							return "", nil, err
+						}
+						if ok {
 							// Recursively expand the replacement value
-							expandedReplacement, nestedExpanded, err := expandString(replacement, lookup)
+							expandedReplacement, nestedExpanded, err := x.expandString(replacement, lookup)
 							if err != nil {
 								return "", nil, err
 							}
@@ -248,9 +510,15 @@ func expandString(value string, lookup LookupFn) (string, map[string]Location, e
 						// Otherwise leave empty
 					} else {
 						// Simple ${VAR} syntax
-						if variable, ok := lookup(content); ok {
+						variable, ok, err := lookup(x.ctx, content)
+						if err != nil {
+							return "", nil, err
+						}
+						if ok {
 							result.WriteString(variable.Value)
 							expanded[content] = variable.Location
+						} else {
+							x.undefinedReference(content)
 						}
 						// If variable not found, leave it empty (standard behavior)
 					}
@@ -259,6 +527,26 @@ func expandString(value string, lookup LookupFn) (string, map[string]Location, e
 					// No closing brace, write literal
 					result.WriteByte(value[i])
 				}
+			} else if value[i+1] == '(' {
+				// $(command) syntax, only run with a CommandRunner
+				endIdx := findClosingParen(value, i+2)
+				if endIdx == -1 {
+					// No closing parenthesis, write literal
+					result.WriteByte(value[i])
+					continue
+				}
+				if x.options.runner == nil {
+					// Keep the command literally
+					result.WriteString(value[i : endIdx+1])
+				} else {
+					output, err := x.options.runner.Run(x.ctx, value[i+2:endIdx])
+					if err != nil {
+						return "", nil, &ExpansionError{Kind: CommandFailed, Reference: value[i+2 : endIdx], Err: err}
+					}
+					// Like shells, drop the trailing newlines of the output
+					result.WriteString(strings.TrimRight(output, "\n"))
+				}
+				i = endIdx
 			} else if isVarNameChar(value[i+1]) {
 				// $VARIABLE syntax
 				j := i + 1
@@ -266,9 +554,15 @@ func expandString(value string, lookup LookupFn) (string, map[string]Location, e
 					j++
 				}
 				varName := value[i+1 : j]
-				if variable, ok := lookup(varName); ok {
+				variable, ok, err := lookup(x.ctx, varName)
+				if err != nil {
+					return "", nil, err
+				}
+				if ok {
 					result.WriteString(variable.Value)
 					expanded[varName] = variable.Location
+				} else {
+					x.undefinedReference(varName)
 				}
 				// If variable not found, leave it empty
 				i = j - 1 // will be incremented by loop
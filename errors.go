@@ -0,0 +1,197 @@
+package dotenv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ErrorKind identifies the kind of problem reported by a ParseError, an ExpansionError or a TemplateError
+type ErrorKind int
+
+const (
+	// MissingSeparator reports a line without a = or : separator
+	MissingSeparator ErrorKind = iota + 1
+	// InvalidName reports a variable name that doesn't match [A-Za-z0-9_.-]
+	InvalidName
+	// UnterminatedQuote reports a quoted value without a closing quote
+	UnterminatedQuote
+	// RequiredVariableUnset reports a ${VAR?error} or ${VAR:?error} reference to an unset variable
+	RequiredVariableUnset
+	// UnsetExport reports an "export VARIABLE" line for a variable that isn't defined
+	UnsetExport
+	// UndefinedVariable reports a reference to an undefined variable when resolving in strict mode
+	UndefinedVariable
+	// BadSubstitution reports an invalid bash string operation, like a non-numeric ${VAR:offset}
+	BadSubstitution
+	// FilterFailed reports an unknown filter or a filter failure in a ${VAR|filter} pipeline
+	FilterFailed
+	// CommandFailed reports a failure to run the command of a $(command) substitution
+	CommandFailed
+	// LookupFailed reports an error returned by the lookup function while resolving a reference
+	LookupFailed
+	// MissingVariable reports a variable of a template that isn't defined
+	MissingVariable
+	// ExtraVariable reports a variable that isn't defined by the template
+	ExtraVariable
+	// UnfilledPlaceholder reports a variable still set to the placeholder value of the template
+	UnfilledPlaceholder
+)
+
+// String returns a human readable name for the error kind
+func (k ErrorKind) String() string {
+	switch k {
+	case MissingSeparator:
+		return "missing separator"
+	case InvalidName:
+		return "invalid name"
+	case UnterminatedQuote:
+		return "unterminated quote"
+	case RequiredVariableUnset:
+		return "required variable unset"
+	case UnsetExport:
+		return "export of unset variable"
+	case UndefinedVariable:
+		return "undefined variable"
+	case BadSubstitution:
+		return "bad substitution"
+	case FilterFailed:
+		return "filter failed"
+	case CommandFailed:
+		return "command failed"
+	case LookupFailed:
+		return "lookup failed"
+	case MissingVariable:
+		return "missing variable"
+	case ExtraVariable:
+		return "extra variable"
+	case UnfilledPlaceholder:
+		return "unfilled placeholder"
+	default:
+		return fmt.Sprintf("ErrorKind(%d)", int(k))
+	}
+}
+
+// ParseError describes a syntax error found while parsing an .env file
+type ParseError struct {
+	Kind ErrorKind
+	// File is the source name of the parsed file, empty when unknown
+	File string
+	// Line and Column are 1-based positions of the offending text
+	Line   int
+	Column int
+	// Snippet is the offending text, e.g. the invalid name or the whole line
+	Snippet string
+}
+
+func (e *ParseError) Error() string {
+	var msg string
+	switch e.Kind {
+	case MissingSeparator:
+		msg = fmt.Sprintf("line %d: no separator found in line: %s", e.Line, e.Snippet)
+	case InvalidName:
+		msg = fmt.Sprintf("line %d: invalid variable name %q", e.Line, e.Snippet)
+	case UnterminatedQuote:
+		msg = fmt.Sprintf("line %d: unterminated quoted value: %s", e.Line, e.Snippet)
+	case UnsetExport:
+		msg = fmt.Sprintf("line %d %q has an unset variable", e.Line, e.Snippet)
+	default:
+		msg = fmt.Sprintf("line %d: %s: %s", e.Line, e.Kind, e.Snippet)
+	}
+	if e.File != "" {
+		return e.File + ": " + msg
+	}
+	return msg
+}
+
+// ExpansionError describes a failure to expand a variable reference
+type ExpansionError struct {
+	Kind ErrorKind
+	// Variable is the name of the variable being expanded and Location where it is defined
+	Variable string
+	Location Location
+	// Reference is the name of the referenced variable that caused the error
+	Reference string
+	// Column is the 1-based position of the reference in the raw value of the variable, 0 when unknown
+	Column int
+	// Snippet is the text of the reference, e.g. ${VAR:?error}
+	Snippet string
+	// Message is the custom error message set by ${VAR?message}, if any
+	Message string
+	// Err is the underlying error, if any
+	Err error
+}
+
+func (e *ExpansionError) Error() string {
+	var msg string
+	switch {
+	case e.Message != "":
+		msg = e.Message
+	case e.Kind == UndefinedVariable:
+		msg = fmt.Sprintf("%s: undefined variable %s", e.Variable, e.Reference)
+	case e.Kind == BadSubstitution:
+		msg = fmt.Sprintf("%s: bad substitution of %s", e.Variable, e.Reference)
+	case e.Kind == FilterFailed:
+		msg = fmt.Sprintf("%s: %v", e.Variable, e.Err)
+	case e.Kind == CommandFailed:
+		msg = fmt.Sprintf("%s: $(%s): %v", e.Variable, e.Reference, e.Err)
+	case e.Kind == LookupFailed:
+		msg = fmt.Sprintf("%s: lookup of %s: %v", e.Variable, e.Reference, e.Err)
+	default:
+		msg = fmt.Sprintf("%s: required variable is not set", e.Reference)
+	}
+	if file, line, ok := strings.Cut(string(e.Location), ":"); ok && file == "" {
+		// Without a source name, report the line like ParseError does
+		return fmt.Sprintf("line %s: %s", line, msg)
+	}
+	if e.Location != "" {
+		return fmt.Sprintf("%s: %s", e.Location, msg)
+	}
+	return msg
+}
+
+func (e *ExpansionError) Unwrap() error {
+	return e.Err
+}
+
+// CycleError reports variables referencing each other in a cycle, which can't be resolved in dependency order
+type CycleError struct {
+	// Variables names the variables of the cycle, starting and ending with the same variable
+	Variables []string
+	// Locations are the locations of the definitions of Variables
+	Locations []Location
+}
+
+func (e *CycleError) Error() string {
+	return "circular reference: " + strings.Join(e.Variables, " -> ")
+}
+
+// TemplateError reports a difference between an .env file and its template
+type TemplateError struct {
+	Kind     ErrorKind
+	Variable string
+	// Location is where the variable is defined in the .env file, or in the template for a MissingVariable
+	Location Location
+	// Placeholder is the placeholder value of an UnfilledPlaceholder
+	Placeholder string
+}
+
+func (e *TemplateError) Error() string {
+	var msg string
+	switch e.Kind {
+	case MissingVariable:
+		msg = "declared by the template but not defined"
+	case ExtraVariable:
+		msg = "not declared by the template"
+	case UnfilledPlaceholder:
+		msg = fmt.Sprintf("placeholder value %q of the template was not replaced", e.Placeholder)
+		if e.Placeholder == "" {
+			msg = "empty value of the template was not filled in"
+		}
+	default:
+		msg = e.Kind.String()
+	}
+	if e.Location != "" {
+		return fmt.Sprintf("%s: %s: %s", e.Location, e.Variable, msg)
+	}
+	return fmt.Sprintf("%s: %s", e.Variable, msg)
+}
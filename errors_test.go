@@ -0,0 +1,213 @@
+package dotenv_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/compose-spec/dotenv"
+	"gotest.tools/v3/assert"
+)
+
+func TestParseError(t *testing.T) {
+	type test struct {
+		name   string
+		input  string
+		expect dotenv.ParseError
+		err    string
+	}
+	tests := []test{
+		{
+			name:  "missing separator",
+			input: "FOO=BAR\n  INVALIDLINE",
+			expect: dotenv.ParseError{
+				Kind:    dotenv.MissingSeparator,
+				File:    "app.env",
+				Line:    2,
+				Column:  3,
+				Snippet: "  INVALIDLINE",
+			},
+			err: "app.env: line 2: no separator found in line:   INVALIDLINE",
+		},
+		{
+			name:  "invalid name",
+			input: "  FOO BAR=value",
+			expect: dotenv.ParseError{
+				Kind:    dotenv.InvalidName,
+				File:    "app.env",
+				Line:    1,
+				Column:  3,
+				Snippet: "FOO BAR",
+			},
+			err: "app.env: line 1: invalid variable name \"FOO BAR\"",
+		},
+		{
+			name:  "invalid name after export",
+			input: "export 1FOO=value",
+			expect: dotenv.ParseError{
+				Kind:    dotenv.InvalidName,
+				File:    "app.env",
+				Line:    1,
+				Column:  8,
+				Snippet: "1FOO",
+			},
+			err: "app.env: line 1: invalid variable name \"1FOO\"",
+		},
+		{
+			name:  "unset export",
+			input: "FOO=BAR\nexport UNDEFINED",
+			expect: dotenv.ParseError{
+				Kind:    dotenv.UnsetExport,
+				File:    "app.env",
+				Line:    2,
+				Column:  8,
+				Snippet: "UNDEFINED",
+			},
+			err: "app.env: line 2 \"UNDEFINED\" has an unset variable",
+		},
+		{
+			name:  "unterminated double quote",
+			input: "FOO=BAR\nBAZ= \"multi\nline",
+			expect: dotenv.ParseError{
+				Kind:    dotenv.UnterminatedQuote,
+				File:    "app.env",
+				Line:    2,
+				Column:  6,
+				Snippet: "BAZ= \"multi",
+			},
+			err: "app.env: line 2: unterminated quoted value: BAZ= \"multi",
+		},
+		{
+			name:  "unterminated single quote",
+			input: "FOO='bar",
+			expect: dotenv.ParseError{
+				Kind:    dotenv.UnterminatedQuote,
+				File:    "app.env",
+				Line:    1,
+				Column:  5,
+				Snippet: "FOO='bar",
+			},
+			err: "app.env: line 1: unterminated quoted value: FOO='bar",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := dotenv.Parse(context.TODO(), strings.NewReader(test.input), dotenv.WithSourceName("app.env"))
+			assert.Error(t, err, test.err)
+			var parseErr *dotenv.ParseError
+			assert.Assert(t, errors.As(err, &parseErr))
+			assert.DeepEqual(t, test.expect, *parseErr)
+		})
+	}
+}
+
+func TestExpansionError(t *testing.T) {
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader("FOO=bar\nBAR=${UNSET:?}"), dotenv.WithSourceName("app.env"))
+	assert.NilError(t, err)
+
+	_, err = env.Resolve(nil)
+	assert.Error(t, err, "app.env:2: UNSET: required variable is not set")
+	var expansionErr *dotenv.ExpansionError
+	assert.Assert(t, errors.As(err, &expansionErr))
+	assert.DeepEqual(t, dotenv.ExpansionError{
+		Kind:      dotenv.RequiredVariableUnset,
+		Variable:  "BAR",
+		Location:  "app.env:2",
+		Reference: "UNSET",
+		Column:    1,
+		Snippet:   "${UNSET:?}",
+	}, *expansionErr)
+}
+
+func TestResolveWithStrict(t *testing.T) {
+	input := strings.Join([]string{
+		"HOST=db",
+		"URL=http://${HOST}:$PROT/$DATABSE_NAME/$PROT",
+		"PORT=${PORT:-5432}",
+		"DEBUG=${DEBUG-}${VERBOSE:+-v}",
+		"RAW='$UNDEFINED'",
+		"NAME=${APP_NAME:-$DEFAULT_NAME}",
+		"USER=$USER",
+	}, "\n")
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader(input), dotenv.WithSourceName("app.env"))
+	assert.NilError(t, err)
+
+	lookup := func(name string) (dotenv.Variable, bool) {
+		if name == "USER" {
+			return dotenv.Variable{Name: name, Value: "admin"}, true
+		}
+		return dotenv.Variable{}, false
+	}
+	_, err = env.Resolve(lookup, dotenv.WithStrict())
+	assert.Error(t, err, strings.Join([]string{
+		"app.env:2: URL: undefined variable PROT",
+		"app.env:2: URL: undefined variable DATABSE_NAME",
+		"app.env:6: NAME: undefined variable DEFAULT_NAME",
+	}, "\n"))
+
+	var undefined []dotenv.ExpansionError
+	for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var expansionErr *dotenv.ExpansionError
+		assert.Assert(t, errors.As(err, &expansionErr))
+		undefined = append(undefined, *expansionErr)
+	}
+	assert.DeepEqual(t, []dotenv.ExpansionError{
+		{Kind: dotenv.UndefinedVariable, Variable: "URL", Location: "app.env:2", Reference: "PROT", Column: 16, Snippet: "$PROT"},
+		{Kind: dotenv.UndefinedVariable, Variable: "URL", Location: "app.env:2", Reference: "DATABSE_NAME", Column: 22, Snippet: "$DATABSE_NAME"},
+		{Kind: dotenv.UndefinedVariable, Variable: "NAME", Location: "app.env:6", Reference: "DEFAULT_NAME", Column: 13, Snippet: "$DEFAULT_NAME"},
+	}, undefined)
+
+	// Without strict mode, undefined variables expand to an empty string
+	vars, err := env.Resolve(lookup)
+	assert.NilError(t, err)
+	assert.Equal(t, vars["URL"], "http://db://")
+}
+
+func TestParseWithErrorRecovery(t *testing.T) {
+	input := strings.Join([]string{
+		"FOO=foo",
+		"INVALIDLINE",
+		"BAR BAZ=value",
+		"BAR=bar",
+		"export UNDEFINED",
+		"export BAR",
+		"QUX=\"unterminated",
+		"IGNORED=value",
+	}, "\n")
+
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader(input), dotenv.WithErrorRecovery())
+	assert.Error(t, err, strings.Join([]string{
+		"line 2: no separator found in line: INVALIDLINE",
+		"line 3: invalid variable name \"BAR BAZ\"",
+		"line 5 \"UNDEFINED\" has an unset variable",
+		"line 7: unterminated quoted value: QUX=\"unterminated",
+	}, "\n"))
+
+	var kinds []dotenv.ErrorKind
+	for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var parseErr *dotenv.ParseError
+		assert.Assert(t, errors.As(err, &parseErr))
+		kinds = append(kinds, parseErr.Kind)
+	}
+	assert.DeepEqual(t, []dotenv.ErrorKind{
+		dotenv.MissingSeparator,
+		dotenv.InvalidName,
+		dotenv.UnsetExport,
+		dotenv.UnterminatedQuote,
+	}, kinds)
+
+	vars, err := env.Resolve(nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[string]string{
+		"FOO": "foo",
+		"BAR": "bar",
+	}, vars)
+}
+
+func TestParseWithErrorRecoveryValidInput(t *testing.T) {
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader("FOO=foo"), dotenv.WithErrorRecovery())
+	assert.NilError(t, err)
+	assert.Equal(t, len(env.Variables), 1)
+}
@@ -0,0 +1,184 @@
+package dotenv
+
+import (
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// Filter transforms the values flowing through a ${VAR|filter:arg} pipeline. The pipeline starts with the value
+// of the variable, arg is the expanded text following the : of the filter, empty if there is none
+type Filter func(values []string, arg string) ([]string, error)
+
+// builtinFilters are the filters available to all pipelines
+var builtinFilters = map[string]Filter{
+	"lower":   eachValue(strings.ToLower),
+	"upper":   eachValue(strings.ToUpper),
+	"trim":    trimFilter,
+	"default": defaultFilter,
+	"split":   splitFilter,
+	"join":    joinFilter,
+	"base64encode": eachValue(func(value string) string {
+		return base64.StdEncoding.EncodeToString([]byte(value))
+	}),
+	"base64decode": base64DecodeFilter,
+}
+
+// WithFilters registers filters for ${VAR|filter:arg} pipelines, in addition to the builtin lower, upper, trim,
+// default, split, join, base64encode and base64decode filters. A registered filter replaces the builtin filter
+// of the same name
+func WithFilters(filters map[string]Filter) ResolveOption {
+	return func(o *resolveOptions) {
+		if o.filters == nil {
+			o.filters = make(map[string]Filter)
+		}
+		maps.Copy(o.filters, filters)
+	}
+}
+
+// filterCall is a filter applied in a pipeline
+type filterCall struct {
+	name string
+	arg  string
+	// argStart is the offset of arg in the ${...} content
+	argStart int
+}
+
+// parsePipeline parses the content of a ${VAR|filter:arg|...} reference into the variable name and filters to apply
+func parsePipeline(content string) (string, []filterCall, bool) {
+	pipes := findPipes(content)
+	if len(pipes) == 0 {
+		return "", nil, false
+	}
+	name := content[:pipes[0]]
+	if name == "" || varNameLength(name) != len(name) {
+		return "", nil, false
+	}
+
+	calls := make([]filterCall, len(pipes))
+	for k, start := range pipes {
+		end := len(content)
+		if k+1 < len(pipes) {
+			end = pipes[k+1]
+		}
+		segment := content[start+1 : end]
+		filterName, arg, _ := strings.Cut(segment, ":")
+		calls[k] = filterCall{
+			name:     strings.TrimSpace(filterName),
+			arg:      arg,
+			argStart: start + 1 + len(filterName) + 1,
+		}
+	}
+	return name, calls, true
+}
+
+// expandPipeline applies the filters to the value of the variable. Remaining values are joined with a comma
+func (x *expander) expandPipeline(name string, calls []filterCall, lookup ContextLookupFn, expanded map[string]Location) (string, error) {
+	variable, ok, err := lookup(x.ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		expanded[name] = variable.Location
+	} else if !hasDefault(calls) {
+		x.undefinedReference(name)
+	}
+
+	values := []string{variable.Value}
+	for _, call := range calls {
+		filter, ok := x.options.filters[call.name]
+		if !ok {
+			filter, ok = builtinFilters[call.name]
+		}
+		if !ok {
+			return "", &ExpansionError{Kind: FilterFailed, Reference: name, Err: fmt.Errorf("unknown filter %q", call.name)}
+		}
+
+		arg, nestedExpanded, err := x.expandString(call.arg, lookup)
+		if err != nil {
+			return "", err
+		}
+		for k, v := range nestedExpanded {
+			expanded[k] = v
+		}
+		if values, err = filter(values, arg); err != nil {
+			return "", &ExpansionError{Kind: FilterFailed, Reference: name, Err: fmt.Errorf("%s: %w", call.name, err)}
+		}
+	}
+	return strings.Join(values, ","), nil
+}
+
+// hasDefault returns true if the pipeline sets a default value for unset variables
+func hasDefault(calls []filterCall) bool {
+	for _, call := range calls {
+		if call.name == "default" {
+			return true
+		}
+	}
+	return false
+}
+
+// eachValue makes a filter applying fn to every value
+func eachValue(fn func(string) string) Filter {
+	return func(values []string, _ string) ([]string, error) {
+		result := make([]string, len(values))
+		for i, value := range values {
+			result[i] = fn(value)
+		}
+		return result, nil
+	}
+}
+
+// trimFilter removes leading and trailing white space, or the characters of arg if set
+func trimFilter(values []string, arg string) ([]string, error) {
+	if arg == "" {
+		return eachValue(strings.TrimSpace)(values, arg)
+	}
+	return eachValue(func(value string) string {
+		return strings.Trim(value, arg)
+	})(values, arg)
+}
+
+// defaultFilter replaces an empty value with arg
+func defaultFilter(values []string, arg string) ([]string, error) {
+	if strings.Join(values, "") == "" {
+		return []string{arg}, nil
+	}
+	return values, nil
+}
+
+// splitFilter splits values around arg, a comma by default. Empty values are dropped
+func splitFilter(values []string, arg string) ([]string, error) {
+	if arg == "" {
+		arg = ","
+	}
+	var result []string
+	for _, value := range values {
+		if value != "" {
+			result = append(result, strings.Split(value, arg)...)
+		}
+	}
+	return result, nil
+}
+
+// joinFilter joins values with arg, a comma by default
+func joinFilter(values []string, arg string) ([]string, error) {
+	if arg == "" {
+		arg = ","
+	}
+	return []string{strings.Join(values, arg)}, nil
+}
+
+// base64DecodeFilter decodes standard base64 encoded values
+func base64DecodeFilter(values []string, _ string) ([]string, error) {
+	result := make([]string, len(values))
+	for i, value := range values {
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = string(decoded)
+	}
+	return result, nil
+}
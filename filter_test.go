@@ -0,0 +1,109 @@
+package dotenv_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/compose-spec/dotenv"
+	"gotest.tools/v3/assert"
+)
+
+func TestResolveWithFilters(t *testing.T) {
+	type test struct {
+		name   string
+		value  string
+		expect string
+		err    string
+	}
+	tests := []test{
+		{name: "lower", value: "${HOST|lower}", expect: "db.example.com"},
+		{name: "upper", value: "${ENV|upper}", expect: "PRODUCTION"},
+		{name: "trim", value: "${PADDED|trim}", expect: "padded"},
+		{name: "trim characters", value: "${PATHS|trim:/}", expect: "usr/bin"},
+		{name: "default of unset", value: "${PORT|default:8080}", expect: "8080"},
+		{name: "default of empty", value: "${EMPTY|default:8080}", expect: "8080"},
+		{name: "default of set", value: "${ENV|default:dev}", expect: "production"},
+		{name: "default from variable", value: "${PORT|default:${FALLBACK_PORT}}", expect: "9090"},
+		{name: "split and join", value: "${LIST|split:,|join:;}", expect: "a;b;c"},
+		{name: "split, transform and join", value: "${LIST|split|upper|join: }", expect: "A B C"},
+		{name: "split without join", value: "${LIST|split:,}", expect: "a,b,c"},
+		{name: "base64 decode", value: "${SECRET|base64decode}", expect: "s3cr3t"},
+		{name: "base64 encode", value: "${ENV|base64encode}", expect: "cHJvZHVjdGlvbg=="},
+		{name: "nested pipeline", value: "${UNSET:-${HOST|upper}}", expect: "DB.EXAMPLE.COM"},
+		{name: "custom filter", value: "${HOST|suffix:-replica}", expect: "DB.example.com-replica"},
+		{name: "unknown filter", value: "${HOST|unknown}", err: `line 1: VALUE: unknown filter "unknown"`},
+		{name: "failing filter", value: "${HOST|base64decode}", err: "line 1: VALUE: base64decode: illegal base64 data at input byte 2"},
+		{name: "not a pipeline", value: "${UNSET:-a|b}", expect: "a|b"},
+	}
+
+	lookup := func(name string) (dotenv.Variable, bool) {
+		values := map[string]string{
+			"HOST":          "DB.example.com",
+			"ENV":           "production",
+			"PADDED":        "  padded  ",
+			"PATHS":         "/usr/bin/",
+			"EMPTY":         "",
+			"FALLBACK_PORT": "9090",
+			"LIST":          "a,b,c",
+			"SECRET":        "czNjcjN0",
+		}
+		value, ok := values[name]
+		return dotenv.Variable{Name: name, Value: value}, ok
+	}
+	filters := map[string]dotenv.Filter{
+		"suffix": func(values []string, arg string) ([]string, error) {
+			for i := range values {
+				values[i] += arg
+			}
+			return values, nil
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env, err := dotenv.Parse(context.TODO(), strings.NewReader(`VALUE="`+test.value+`"`))
+			assert.NilError(t, err)
+			vars, err := env.Resolve(lookup, dotenv.WithFilters(filters))
+			if test.err != "" {
+				assert.Error(t, err, test.err)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, vars["VALUE"], test.expect)
+		})
+	}
+}
+
+func TestResolveWithFiltersProvenance(t *testing.T) {
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader("HOST=DB\nPORT=5432\nURL=${HOST|lower}:${UNSET|default:$PORT}"), dotenv.WithSourceName(".env"))
+	assert.NilError(t, err)
+	resolved, err := env.ResolveEnv(context.TODO(), nil, dotenv.WithStrict())
+	assert.NilError(t, err)
+	url, ok := resolved.Lookup("URL")
+	assert.Assert(t, ok)
+	assert.Equal(t, url.Value, "db:5432")
+	assert.DeepEqual(t, map[string]dotenv.Location{"HOST": ".env:1", "PORT": ".env:2"}, url.Expanded)
+
+	graph := env.Graph()
+	assert.DeepEqual(t, []dotenv.Reference{
+		{Name: "HOST", Location: ".env:3", Offset: 0, Definition: ".env:1"},
+		{Name: "UNSET", Location: ".env:3", Offset: 14},
+		{Name: "PORT", Location: ".env:3", Offset: 30, Definition: ".env:2"},
+	}, graph.Variables[2].References)
+}
+
+func TestFilterError(t *testing.T) {
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader("SECRET=${RAW|base64decode}"), dotenv.WithSourceName(".env"))
+	assert.NilError(t, err)
+	_, err = env.Resolve(func(name string) (dotenv.Variable, bool) {
+		return dotenv.Variable{Name: name, Value: "%%%"}, true
+	})
+	var expansionErr *dotenv.ExpansionError
+	assert.Assert(t, errors.As(err, &expansionErr))
+	assert.Equal(t, expansionErr.Kind, dotenv.FilterFailed)
+	assert.Equal(t, expansionErr.Location, dotenv.Location(".env:1"))
+	var corrupt base64.CorruptInputError
+	assert.Assert(t, errors.As(err, &corrupt))
+}
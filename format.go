@@ -0,0 +1,189 @@
+package dotenv
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// FormatOption configures the behavior of Format
+type FormatOption func(*formatOptions)
+
+type formatOptions struct {
+	sortKeys bool
+}
+
+// WithSortedKeys sorts variables by name within each block of lines delimited by blank lines and comment lines.
+// Comment lines starting a block, like a section header, stay in place above the sorted variables.
+// Blocks are left unsorted when sorting would change the resolved values
+func WithSortedKeys() FormatOption {
+	return func(o *formatOptions) {
+		o.sortKeys = true
+	}
+}
+
+// Format reads an .env file from the provided reader and returns it in canonical form:
+// variables use = as separator without surrounding whitespace, values use the simplest quoting that preserves them,
+// trailing whitespace is removed, consecutive blank lines are collapsed and lines end with \n
+func Format(ctx context.Context, reader io.Reader, opts ...FormatOption) ([]byte, error) {
+	var options formatOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	envFile, err := Parse(ctx, reader)
+	if err != nil {
+		return nil, err
+	}
+	envFile, err = envFile.format(options)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if _, err := envFile.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// IsFormatted reports whether the .env file read from the provided reader is already in the canonical form
+// produced by Format
+func IsFormatted(ctx context.Context, reader io.Reader, opts ...FormatOption) (bool, error) {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return false, err
+	}
+	formatted, err := Format(ctx, bytes.NewReader(content), opts...)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(content, formatted), nil
+}
+
+// format returns a copy of the EnvFile with its syntax tree in canonical form
+func (e *EnvFile) format(options formatOptions) (*EnvFile, error) {
+	entries, err := e.entries()
+	if err != nil {
+		return nil, err
+	}
+
+	formatted := make([]entry, 0, len(entries))
+	for _, en := range entries {
+		node := en.node
+		switch node.Kind {
+		case BlankNode:
+			// Drop leading and consecutive blank lines
+			if len(formatted) == 0 || formatted[len(formatted)-1].node.Kind == BlankNode {
+				continue
+			}
+		case CommentNode, InvalidNode:
+			node.Text = strings.TrimRightFunc(node.Text, unicode.IsSpace)
+		case ExportNode:
+			node.Text = "export " + node.Name
+		case VariableNode:
+			raw, style := canonicalValue(en.variable.RawValue, en.variable.Quoted)
+			text, err := quoteValue(raw, style)
+			if err != nil {
+				return nil, err
+			}
+			comment := strings.TrimSpace(node.Trailing)
+			if comment != "" && style != Unquoted {
+				// A quoted value can't be followed by a comment, move it above the variable
+				formatted = append(formatted, entry{node: Node{Kind: CommentNode, Text: comment, EOL: "\n"}})
+				comment = ""
+			}
+			if node.Export != "" {
+				node.Export = "export "
+			}
+			node.Indent, node.SpaceBefore, node.Separator, node.SpaceAfter = "", "", "=", ""
+			node.Value = text
+			node.Trailing = ""
+			if comment != "" {
+				node.Trailing = " " + comment
+			}
+			en.variable.RawValue, en.variable.Quoted = raw, style
+		}
+		node.EOL = "\n"
+		formatted = append(formatted, entry{node: node, variable: en.variable})
+	}
+	// Drop trailing blank lines
+	for len(formatted) > 0 && formatted[len(formatted)-1].node.Kind == BlankNode {
+		formatted = formatted[:len(formatted)-1]
+	}
+
+	if options.sortKeys {
+		formatted = sortBlocks(formatted)
+	}
+
+	return withEntries(formatted), nil
+}
+
+// canonicalValue returns the simplest raw value and quote style equivalent to the raw value and quote style of a variable
+func canonicalValue(raw string, style QuoteStyle) (string, QuoteStyle) {
+	if style == Quoted || !strings.Contains(raw, "$") {
+		// The value is used literally
+		return literalValue(raw)
+	}
+	return raw, expandableValue(raw)
+}
+
+// sortBlocks sorts variables by name within each block of entries, unless that changes the resolved values.
+// Blocks are delimited by blank lines and by comment lines following a variable, which are the header of the block
+func sortBlocks(entries []entry) []entry {
+	reference, ok := resolveEntries(entries)
+	if !ok {
+		return entries
+	}
+
+	for start := 0; start < len(entries); {
+		end := start + 1
+		for end < len(entries) && entries[end].node.Kind != BlankNode && !(isDefinition(entries[end-1]) && !isDefinition(entries[end])) {
+			end++
+		}
+
+		sorted := slices.Concat(entries[:start], sortBlock(entries[start:end]), entries[end:])
+		if resolved, ok := resolveEntries(sorted); ok && maps.Equal(reference, resolved) {
+			entries = sorted
+		}
+		start = end
+	}
+	return entries
+}
+
+// isDefinition returns whether the entry defines or exports a variable
+func isDefinition(en entry) bool {
+	return en.node.Kind == VariableNode || en.node.Kind == ExportNode
+}
+
+// sortBlock sorts the variables of a block of entries by name, keeping the header lines preceding them in place
+func sortBlock(block []entry) []entry {
+	header := 0
+	for header < len(block) && !isDefinition(block[header]) {
+		header++
+	}
+	sorted := slices.Clone(block)
+	slices.SortStableFunc(sorted[header:], func(a, b entry) int {
+		return strings.Compare(a.node.Name, b.node.Name)
+	})
+	return sorted
+}
+
+// resolveEntries resolves the variables of entries, replacing references to undefined variables by
+// a marker naming them so that the result reflects which definition each reference is bound to
+func resolveEntries(entries []entry) (map[string]string, bool) {
+	envFile := &EnvFile{}
+	for _, en := range entries {
+		if en.variable != nil {
+			envFile.Variables = append(envFile.Variables, *en.variable)
+		}
+	}
+	resolved, err := envFile.Resolve(func(name string) (Variable, bool) {
+		return Variable{Name: name, Value: "\x00" + name + "\x00"}, true
+	})
+	return resolved, err == nil
+}
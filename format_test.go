@@ -0,0 +1,107 @@
+package dotenv_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/compose-spec/dotenv"
+	"gotest.tools/v3/assert"
+)
+
+func TestFormat(t *testing.T) {
+	type test struct {
+		name   string
+		input  string
+		opts   []dotenv.FormatOption
+		expect string
+	}
+	tests := []test{
+		{
+			name:   "already formatted",
+			input:  "# comment\nFOO=bar\n\nBAR=baz # inline\n",
+			expect: "# comment\nFOO=bar\n\nBAR=baz # inline\n",
+		},
+		{
+			name:   "separators and whitespace",
+			input:  "  FOO : bar  \nexport   BAR =  baz   #  inline  \n# comment   \n",
+			expect: "FOO=bar\nexport BAR=baz #  inline\n# comment\n",
+		},
+		{
+			name:   "blank lines",
+			input:  "\n\nFOO=bar\n\n\n\nBAR=baz\n\n",
+			expect: "FOO=bar\n\nBAR=baz\n",
+		},
+		{
+			name:   "line endings",
+			input:  "FOO=bar\r\nBAR=baz",
+			expect: "FOO=bar\nBAR=baz\n",
+		},
+		{
+			name:   "minimal quoting",
+			input:  "A=\"plain\"\nB='plain'\nC=\"a # b\"\nD=\"$A\"\nE='$A'\nF=\"it's\"\nG=\"multi\nline\"\nH=\"\\$A\"\n",
+			expect: "A=plain\nB=plain\nC='a # b'\nD=$A\nE='$A'\nF=it's\nG=\"multi\\nline\"\nH=\\$A\n",
+		},
+		{
+			name:   "export without assignment",
+			input:  "FOO=bar\nexport FOO  \n",
+			expect: "FOO=bar\nexport FOO\n",
+		},
+		{
+			name:   "sorted keys with comment headers",
+			input:  "# App\nB=b\nA=a\n# Database\nDB_PORT=5432\nDB_HOST=db\n",
+			opts:   []dotenv.FormatOption{dotenv.WithSortedKeys()},
+			expect: "# App\nA=a\nB=b\n# Database\nDB_HOST=db\nDB_PORT=5432\n",
+		},
+		{
+			name:   "sorted keys within blank line and comment delimited blocks",
+			input:  "# header\n\n# about c\nC=c\nB=b # inline\n# about a\nA=a\n# trailing\n\nZ=z\nY=y\n",
+			opts:   []dotenv.FormatOption{dotenv.WithSortedKeys()},
+			expect: "# header\n\n# about c\nB=b # inline\nC=c\n# about a\nA=a\n# trailing\n\nY=y\nZ=z\n",
+		},
+		{
+			name:   "sorted keys keep exports after definitions",
+			input:  "FOO=a\nexport FOO\nBAR=b\n",
+			opts:   []dotenv.FormatOption{dotenv.WithSortedKeys()},
+			expect: "BAR=b\nFOO=a\nexport FOO\n",
+		},
+		{
+			name:   "blocks are not sorted when it changes values",
+			input:  "C=c\nA=$C\n\nZ=z\nY=y\n",
+			opts:   []dotenv.FormatOption{dotenv.WithSortedKeys()},
+			expect: "C=c\nA=$C\n\nY=y\nZ=z\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out, err := dotenv.Format(context.TODO(), strings.NewReader(test.input), test.opts...)
+			assert.NilError(t, err)
+			assert.Equal(t, string(out), test.expect)
+
+			// Formatting must not change values
+			before, err := dotenv.Parse(context.TODO(), strings.NewReader(test.input))
+			assert.NilError(t, err)
+			expected, err := before.Resolve(nil)
+			assert.NilError(t, err)
+			after, err := dotenv.Parse(context.TODO(), strings.NewReader(string(out)))
+			assert.NilError(t, err)
+			actual, err := after.Resolve(nil)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, expected, actual)
+
+			formatted, err := dotenv.IsFormatted(context.TODO(), strings.NewReader(string(out)), test.opts...)
+			assert.NilError(t, err)
+			assert.Assert(t, formatted)
+		})
+	}
+}
+
+func TestIsFormatted(t *testing.T) {
+	formatted, err := dotenv.IsFormatted(context.TODO(), strings.NewReader("FOO = bar\n"))
+	assert.NilError(t, err)
+	assert.Assert(t, !formatted)
+
+	_, err = dotenv.IsFormatted(context.TODO(), strings.NewReader("INVALID"))
+	assert.Error(t, err, "line 1: no separator found in line: INVALID")
+}
@@ -2,7 +2,6 @@ module github.com/compose-spec/dotenv
 
 go 1.25.4
 
-require (
-	github.com/google/go-cmp v0.5.9 // indirect
-	gotest.tools/v3 v3.5.2 // indirect
-)
+require gotest.tools/v3 v3.5.2
+
+require github.com/google/go-cmp v0.5.9 // indirect
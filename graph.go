@@ -0,0 +1,397 @@
+package dotenv
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Graph is the dependency graph of the variables of an EnvFile
+type Graph struct {
+	// Variables lists the definitions of the file in order, along with the variables they reference
+	Variables []GraphVariable `json:"variables"`
+}
+
+// GraphVariable is a variable definition in a Graph
+type GraphVariable struct {
+	Name       string      `json:"name"`
+	Location   Location    `json:"location"`
+	References []Reference `json:"references"`
+}
+
+// Reference is a reference to a variable found in a raw value
+type Reference struct {
+	// Name is the name of the referenced variable
+	Name string `json:"name"`
+	// Location is the file and line of the reference; for multi-line values it can precede Variable.Location, which is the value's last line
+	Location Location `json:"location"`
+	// Offset is the byte offset of the $ starting the reference in the raw value of the referencing variable
+	Offset int `json:"offset"`
+	// Conditional is true when the reference is only expanded depending on the value of another variable,
+	// like in the default value of ${VAR:-$DEFAULT}
+	Conditional bool `json:"conditional,omitempty"`
+	// Definition is the location of the definition the reference resolves to in dependency order,
+	// empty when the referenced variable isn't defined in the file
+	Definition Location `json:"definition,omitempty"`
+}
+
+// Graph returns the dependency graph of the variables of the EnvFile.
+// References are found by static analysis of raw values, including those nested in default and replacement values,
+// without resolving anything. Single-quoted values have no references.
+// WithDialect selects the syntax of references, other options are ignored
+func (e *EnvFile) Graph(opts ...ResolveOption) *Graph {
+	var options resolveOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	bindings := e.bindings(options.dialect)
+	sources := e.sourceValues()
+	graph := &Graph{Variables: make([]GraphVariable, 0, len(e.Variables))}
+	for i, v := range e.Variables {
+		gv := GraphVariable{Name: v.Name, Location: v.Location, References: []Reference{}}
+		if v.Quoted != Quoted {
+			for _, ref := range findReferences(v.RawValue, options.dialect) {
+				reference := Reference{
+					Name:        ref.name,
+					Location:    v.Location.addLines(lineBreaks(v, sources[i], ref.offset) - lineBreaks(v, sources[i], len(v.RawValue))),
+					Offset:      ref.offset,
+					Conditional: ref.conditional,
+				}
+				if j := bindings[i][ref.name]; j != -1 {
+					reference.Definition = e.Variables[j].Location
+				}
+				gv.References = append(gv.References, reference)
+			}
+		}
+		graph.Variables = append(graph.Variables, gv)
+	}
+	return graph
+}
+
+// sourceValues returns the values of the variables of the EnvFile as written in its syntax tree, or empty values
+// when the EnvFile has no syntax tree matching its variables
+func (e *EnvFile) sourceValues() []string {
+	sources := make([]string, 0, len(e.Variables))
+	for _, node := range e.Nodes {
+		if node.Kind == VariableNode {
+			sources = append(sources, node.Value)
+		}
+	}
+	if len(sources) != len(e.Variables) {
+		return make([]string, len(e.Variables))
+	}
+	return sources
+}
+
+// lineBreaks returns the number of line breaks before the byte offset of the raw value of v in source, the value
+// as written. Escape sequences like \n aren't line breaks, the line breaks of the raw value are counted when source
+// is empty
+func lineBreaks(v Variable, source string, offset int) int {
+	if v.Quoted != DoubleQuoted || !strings.HasPrefix(source, `"`) {
+		return strings.Count(v.RawValue[:offset], "\n")
+	}
+	// Replay unescapeDoubleQuoted until offset bytes of the raw value are produced
+	breaks, n := 0, 0
+	for i := 1; i < len(source) && n < offset; i++ {
+		switch {
+		case source[i] == '\\' && i+1 < len(source) && strings.IndexByte(`ntr\"`, source[i+1]) != -1:
+			i++
+		case source[i] == '\n':
+			breaks++
+		}
+		n++
+	}
+	return breaks
+}
+
+// Dependents returns the names of the variables which reference the variable name, directly or through
+// other variables, in definition order
+func (g *Graph) Dependents(name string) []string {
+	affected := map[string]bool{name: true}
+	for changed := true; changed; {
+		changed = false
+		for _, v := range g.Variables {
+			if affected[v.Name] {
+				continue
+			}
+			for _, ref := range v.References {
+				if affected[ref.Name] {
+					affected[v.Name] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	var dependents []string
+	for _, v := range g.Variables {
+		if v.Name != name && affected[v.Name] && !slices.Contains(dependents, v.Name) {
+			dependents = append(dependents, v.Name)
+		}
+	}
+	return dependents
+}
+
+// addLines returns the location n lines after l, or before l when n is negative
+func (l Location) addLines(n int) Location {
+	idx := strings.LastIndex(string(l), ":")
+	if n == 0 || idx == -1 {
+		return l
+	}
+	line, err := strconv.Atoi(string(l[idx+1:]))
+	if err != nil {
+		return l
+	}
+	return Location(fmt.Sprintf("%s:%d", l[:idx], line+n))
+}
+
+// WriteDOT writes the graph in Graphviz DOT format, with an edge from each variable to the variables it references.
+// Conditional references are drawn dashed and variables not defined in the file are drawn dotted.
+// The tooltip of edges gives the location of the reference and of the definition it resolves to, if any
+func (g *Graph) WriteDOT(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, "digraph dotenv {"); err != nil {
+		return err
+	}
+
+	defined := make(map[string]bool)
+	var lines []string
+	for _, v := range g.Variables {
+		if !defined[v.Name] {
+			defined[v.Name] = true
+			lines = append(lines, fmt.Sprintf("  %q;", v.Name))
+		}
+	}
+	for _, v := range g.Variables {
+		for _, ref := range v.References {
+			if !defined[ref.Name] {
+				defined[ref.Name] = true
+				lines = append(lines, fmt.Sprintf("  %q [style=dotted];", ref.Name))
+			}
+			tooltip := string(ref.Location)
+			if ref.Definition != "" {
+				tooltip += " -> " + string(ref.Definition)
+			}
+			attributes := fmt.Sprintf("tooltip=%q", tooltip)
+			if ref.Conditional {
+				attributes = "style=dashed, " + attributes
+			}
+			edge := fmt.Sprintf("  %q -> %q [%s];", v.Name, ref.Name, attributes)
+			if !slices.Contains(lines, edge) {
+				lines = append(lines, edge)
+			}
+		}
+	}
+
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+// reference is a variable reference found in a raw value
+type reference struct {
+	name string
+	// offset is the byte offset of the $ starting the reference in the raw value
+	offset int
+	// conditional is true when the reference is only expanded depending on the value of another variable,
+	// like in the default value of ${VAR:-$DEFAULT}
+	conditional bool
+}
+
+// referenceOperators are the ${VAR<operator>...} operators, in the order expandString looks for them
+var referenceOperators = []string{":?", ":-", ":+", "?", "-", "+"}
+
+// findReferences statically lists the variable references of a raw value, including those nested in
+// default and replacement values, without resolving anything
+func findReferences(value string, dialect Dialect) []reference {
+	return appendReferences(nil, value, 0, false, dialect)
+}
+
+func appendReferences(refs []reference, value string, base int, conditional bool, dialect Dialect) []reference {
+	for i := 0; i < len(value); i++ {
+		// Skip escaped dollar sign \$
+		if value[i] == '\\' && i+1 < len(value) && value[i+1] == '$' {
+			i++
+			continue
+		}
+		if value[i] != '$' || i+1 >= len(value) {
+			continue
+		}
+
+		if value[i+1] == '{' {
+			endIdx := findClosingBrace(value, i+2)
+			if endIdx == -1 {
+				continue
+			}
+			content := value[i+2 : endIdx]
+			if name, calls, ok := parsePipeline(content); ok {
+				// Filter arguments are always expanded
+				refs = append(refs, reference{name: name, offset: base + i, conditional: conditional})
+				for _, call := range calls {
+					refs = appendReferences(refs, call.arg, base+i+2+call.argStart, conditional, dialect)
+				}
+				i = endIdx
+				continue
+			}
+			if op, ok := parseBashOperation(content); ok && dialect == Bash {
+				// Patterns and replacement strings of bash string operations are always expanded
+				refs = append(refs, reference{name: op.name, offset: base + i, conditional: conditional})
+				refs = appendReferences(refs, op.arg, base+i+2+op.argStart, conditional, dialect)
+				i = endIdx
+				continue
+			}
+			name, nested, nestedStart := content, "", 0
+			for _, operator := range referenceOperators {
+				if idx := findOperator(content, operator); idx != -1 {
+					name = content[:idx]
+					// Error messages of ${VAR?error} are not expanded
+					if operator != "?" && operator != ":?" {
+						nested, nestedStart = content[idx+len(operator):], idx+len(operator)
+					}
+					break
+				}
+			}
+			refs = append(refs, reference{name: name, offset: base + i, conditional: conditional})
+			refs = appendReferences(refs, nested, base+i+2+nestedStart, true, dialect)
+			i = endIdx
+		} else if value[i+1] == '(' {
+			// Commands of $(command) are passed as is to the CommandRunner
+			if endIdx := findClosingParen(value, i+2); endIdx != -1 {
+				i = endIdx
+			}
+		} else if isVarNameChar(value[i+1]) {
+			j := i + 1
+			for j < len(value) && isVarNameChar(value[j]) {
+				j++
+			}
+			refs = append(refs, reference{name: value[i+1 : j], offset: base + i, conditional: conditional})
+			i = j - 1
+		}
+	}
+	return refs
+}
+
+// bindings returns, for each variable of the EnvFile, the index of the definition each of its references resolves to
+// when resolving in dependency order, or -1 for references resolved by the external lookup
+func (e *EnvFile) bindings(dialect Dialect) []map[string]int {
+	definitions := make(map[string][]int)
+	for i, v := range e.Variables {
+		definitions[v.Name] = append(definitions[v.Name], i)
+	}
+
+	bindings := make([]map[string]int, len(e.Variables))
+	for i, v := range e.Variables {
+		bindings[i] = make(map[string]int)
+		if v.Quoted == Quoted {
+			continue
+		}
+		for _, ref := range findReferences(v.RawValue, dialect) {
+			bindings[i][ref.name] = bind(definitions[ref.name], i)
+		}
+	}
+	return bindings
+}
+
+// bind returns the definition a reference made by variable i binds to, among the definitions of the referenced name:
+// the last definition, unless that's variable i itself, in which case the definition before it
+func bind(definitions []int, i int) int {
+	n := len(definitions)
+	switch {
+	case n > 0 && definitions[n-1] != i:
+		return definitions[n-1]
+	case n > 1:
+		return definitions[n-2]
+	default:
+		return -1
+	}
+}
+
+// dependencyOrder returns the indexes of the variables of the EnvFile sorted so that every variable comes
+// after the variables it references, keeping the file order otherwise
+func (e *EnvFile) dependencyOrder(bindings []map[string]int, dialect Dialect) ([]int, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(e.Variables))
+	order := make([]int, 0, len(e.Variables))
+	var stack []int
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			start := slices.Index(stack, i)
+			cycle := &CycleError{}
+			for _, j := range append(slices.Clone(stack[start:]), i) {
+				cycle.Variables = append(cycle.Variables, e.Variables[j].Name)
+				cycle.Locations = append(cycle.Locations, e.Variables[j].Location)
+			}
+			return cycle
+		}
+
+		state[i] = visiting
+		stack = append(stack, i)
+		// Visit dependencies in the order they are referenced
+		for _, ref := range findReferences(e.Variables[i].RawValue, dialect) {
+			if j, ok := bindings[i][ref.name]; ok && j != -1 {
+				if err := visit(j); err != nil {
+					return err
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[i] = visited
+		order = append(order, i)
+		return nil
+	}
+
+	for i := range e.Variables {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// expandInDependencyOrder processes variable expansion of variables, a copy of the variables of the EnvFile,
+// resolving each variable after the variables it references wherever they are defined in the file
+func (e *EnvFile) expandInDependencyOrder(x *expander, variables []Variable, externalLookup ContextLookupFn) error {
+	bindings := e.bindings(x.options.dialect)
+	order, err := e.dependencyOrder(bindings, x.options.dialect)
+	if err != nil {
+		return err
+	}
+
+	for _, i := range order {
+		if variables[i].Quoted == Quoted {
+			variables[i].Value = variables[i].RawValue
+			continue
+		}
+
+		lookup := func(ctx context.Context, name string) (Variable, bool, error) {
+			if j, ok := bindings[i][name]; ok && j != -1 {
+				return variables[j], true, nil
+			}
+			if externalLookup != nil {
+				return externalLookup(ctx, name)
+			}
+			return Variable{}, false, nil
+		}
+		if err := variables[i].expandValue(x, lookup); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package dotenv_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/compose-spec/dotenv"
+	"gotest.tools/v3/assert"
+)
+
+func TestResolveWithDependencyOrder(t *testing.T) {
+	type test struct {
+		name   string
+		input  string
+		expect map[string]string
+		err    string
+	}
+	tests := []test{
+		{
+			name:  "forward reference",
+			input: "URL=http://$HOST:${PORT}\nHOST=db\nPORT=80",
+			expect: map[string]string{
+				"URL":  "http://db:80",
+				"HOST": "db",
+				"PORT": "80",
+			},
+		},
+		{
+			name:  "forward reference in default value",
+			input: "URL=${UNSET:-$HOST}\nHOST=db",
+			expect: map[string]string{
+				"URL":  "db",
+				"HOST": "db",
+			},
+		},
+		{
+			name:  "chained forward references",
+			input: "C=$B-c\nB=$A-b\nA=a",
+			expect: map[string]string{
+				"A": "a",
+				"B": "a-b",
+				"C": "a-b-c",
+			},
+		},
+		{
+			name:  "self reference uses external lookup",
+			input: "PATH=$PATH:/opt/bin",
+			expect: map[string]string{
+				"PATH": "/usr/bin:/opt/bin",
+			},
+		},
+		{
+			name:  "self reference uses previous definition",
+			input: "BAR=$FOO\nFOO=a\nFOO=${FOO}b",
+			expect: map[string]string{
+				"BAR": "ab",
+				"FOO": "ab",
+			},
+		},
+		{
+			name:  "single quoted values are not expanded",
+			input: "A='$B'\nB=b",
+			expect: map[string]string{
+				"A": "$B",
+				"B": "b",
+			},
+		},
+		{
+			name:  "cycle",
+			input: "A=$B\nB=${C:-x}\nC=$A",
+			err:   "circular reference: A -> B -> C -> A",
+		},
+	}
+
+	lookup := func(name string) (dotenv.Variable, bool) {
+		if name == "PATH" {
+			return dotenv.Variable{Name: name, Value: "/usr/bin"}, true
+		}
+		return dotenv.Variable{}, false
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env, err := dotenv.Parse(context.TODO(), strings.NewReader(test.input))
+			assert.NilError(t, err)
+			vars, err := env.Resolve(lookup, dotenv.WithDependencyOrder())
+			if test.err != "" {
+				assert.Error(t, err, test.err)
+				return
+			}
+			assert.NilError(t, err)
+			assert.DeepEqual(t, test.expect, vars)
+		})
+	}
+}
+
+func TestCycleError(t *testing.T) {
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader("FOO=bar\nA=$B\nB=$A"), dotenv.WithSourceName("app.env"))
+	assert.NilError(t, err)
+
+	_, err = env.Resolve(nil, dotenv.WithDependencyOrder())
+	var cycleErr *dotenv.CycleError
+	assert.Assert(t, errors.As(err, &cycleErr))
+	assert.DeepEqual(t, []string{"A", "B", "A"}, cycleErr.Variables)
+	assert.DeepEqual(t, []dotenv.Location{"app.env:2", "app.env:3", "app.env:2"}, cycleErr.Locations)
+}
+
+func TestGraph(t *testing.T) {
+	input := "HOST=db\nPORT=${DB_PORT:-${DEFAULT_PORT}}\nURL=\"http://$HOST:${PORT}\"\nRAW='$HOST'\nLOG=${LEVEL?level required}\nMULTI=\"a\\n$HOST\n$PORT\"\n"
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader(input), dotenv.WithSourceName(".env"))
+	assert.NilError(t, err)
+
+	graph := env.Graph()
+	assert.DeepEqual(t, &dotenv.Graph{Variables: []dotenv.GraphVariable{
+		{Name: "HOST", Location: ".env:1", References: []dotenv.Reference{}},
+		{Name: "PORT", Location: ".env:2", References: []dotenv.Reference{
+			{Name: "DB_PORT", Location: ".env:2", Offset: 0},
+			{Name: "DEFAULT_PORT", Location: ".env:2", Offset: 11, Conditional: true},
+		}},
+		{Name: "URL", Location: ".env:3", References: []dotenv.Reference{
+			{Name: "HOST", Location: ".env:3", Offset: 7, Definition: ".env:1"},
+			{Name: "PORT", Location: ".env:3", Offset: 13, Definition: ".env:2"},
+		}},
+		{Name: "RAW", Location: ".env:4", References: []dotenv.Reference{}},
+		{Name: "LOG", Location: ".env:5", References: []dotenv.Reference{
+			{Name: "LEVEL", Location: ".env:5", Offset: 0},
+		}},
+		// The location of a multi-line variable is its last line, and the escaped \n isn't a line break of the source
+		{Name: "MULTI", Location: ".env:7", References: []dotenv.Reference{
+			{Name: "HOST", Location: ".env:6", Offset: 2, Definition: ".env:1"},
+			{Name: "PORT", Location: ".env:7", Offset: 8, Definition: ".env:2"},
+		}},
+	}}, graph)
+
+	// Resolving doesn't depend on the graph being computed
+	_, err = env.Resolve(func(string) (dotenv.Variable, bool) {
+		return dotenv.Variable{Value: "info"}, true
+	})
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, graph.Dependents("DEFAULT_PORT"), []string{"PORT", "URL", "MULTI"})
+	assert.DeepEqual(t, graph.Dependents("HOST"), []string{"URL", "MULTI"})
+	assert.Assert(t, graph.Dependents("URL") == nil)
+
+	var dot strings.Builder
+	assert.NilError(t, graph.WriteDOT(&dot))
+	assert.Equal(t, dot.String(), `digraph dotenv {
+  "HOST";
+  "PORT";
+  "URL";
+  "RAW";
+  "LOG";
+  "MULTI";
+  "DB_PORT" [style=dotted];
+  "PORT" -> "DB_PORT" [tooltip=".env:2"];
+  "DEFAULT_PORT" [style=dotted];
+  "PORT" -> "DEFAULT_PORT" [style=dashed, tooltip=".env:2"];
+  "URL" -> "HOST" [tooltip=".env:3 -> .env:1"];
+  "URL" -> "PORT" [tooltip=".env:3 -> .env:2"];
+  "LEVEL" [style=dotted];
+  "LOG" -> "LEVEL" [tooltip=".env:5"];
+  "MULTI" -> "HOST" [tooltip=".env:6 -> .env:1"];
+  "MULTI" -> "PORT" [tooltip=".env:7 -> .env:2"];
+}
+`)
+
+	out, err := json.Marshal(graph)
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), `{"variables":[`+
+		`{"name":"HOST","location":".env:1","references":[]},`+
+		`{"name":"PORT","location":".env:2","references":[{"name":"DB_PORT","location":".env:2","offset":0},{"name":"DEFAULT_PORT","location":".env:2","offset":11,"conditional":true}]},`+
+		`{"name":"URL","location":".env:3","references":[{"name":"HOST","location":".env:3","offset":7,"definition":".env:1"},{"name":"PORT","location":".env:3","offset":13,"definition":".env:2"}]},`+
+		`{"name":"RAW","location":".env:4","references":[]},`+
+		`{"name":"LOG","location":".env:5","references":[{"name":"LEVEL","location":".env:5","offset":0}]},`+
+		`{"name":"MULTI","location":".env:7","references":[{"name":"HOST","location":".env:6","offset":2,"definition":".env:1"},{"name":"PORT","location":".env:7","offset":8,"definition":".env:2"}]}]}`)
+
+	// Without syntax tree, the line breaks of raw values are counted
+	graph = (&dotenv.EnvFile{Variables: env.Variables}).Graph()
+	assert.Equal(t, graph.Variables[5].References[0].Location, dotenv.Location(".env:6"))
+}
@@ -0,0 +1,64 @@
+package dotenv
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+)
+
+// Source is an .env file to be loaded by Load
+type Source struct {
+	// Path is the path of the .env file
+	Path string
+	// Optional skips the file if it doesn't exist, like "required: false" on a Compose env_file entry
+	Optional bool
+}
+
+// Load parses and resolves the .env files of sources in order, following the semantics of Compose env_file lists:
+// variables defined by later files override those defined by earlier ones, and can reference them in expansions.
+// References to variables not defined by the files are resolved with the optional lookup function, which is
+// given ctx and whose errors stop the loading. Use ContextLookup to pass a LookupFn like OSEnv.
+// Options apply to the resolution of every file, variables given to WithOverrides are added even when no file is loaded.
+// The Location of each resolved variable tells which file the winning definition comes from
+func Load(ctx context.Context, sources []Source, lookup ContextLookupFn, opts ...ResolveOption) (*ResolvedEnv, error) {
+	resolved := newResolvedEnv()
+
+	external := ContextLookup(resolved.Lookup)
+	if lookup != nil {
+		external = NewCompositeLookup(WithPriority(resolved.Lookup, 1), WithContextPriority(lookup, 0)).LookupContext
+	}
+
+	for _, source := range sources {
+		envFile, err := ParseFile(ctx, source.Path)
+		if err != nil {
+			if source.Optional && errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		fileEnv, err := envFile.ResolveEnv(ctx, external, opts...)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range fileEnv.variables {
+			resolved.set(v)
+		}
+	}
+
+	var options resolveOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if len(options.overrides) > 0 {
+		overrides, err := (&EnvFile{}).ResolveEnv(ctx, nil, WithOverrides(options.overrides...))
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range overrides.variables {
+			if _, ok := resolved.index[v.Name]; !ok {
+				resolved.set(v)
+			}
+		}
+	}
+	return resolved, nil
+}
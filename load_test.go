@@ -0,0 +1,130 @@
+package dotenv_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/compose-spec/dotenv"
+	"gotest.tools/v3/assert"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.env")
+	override := filepath.Join(dir, "override.env")
+	assert.NilError(t, os.WriteFile(base, []byte("HOST=localhost\nPORT=5432\nUSER=${LOGIN}\n"), 0o600))
+	assert.NilError(t, os.WriteFile(override, []byte("HOST=db\nURL=postgres://${USER}@${HOST}:${PORT}\n"), 0o600))
+
+	lookup := func(name string) (dotenv.Variable, bool) {
+		if name == "LOGIN" {
+			return dotenv.Variable{Name: name, Value: "admin", Location: ":os"}, true
+		}
+		return dotenv.Variable{}, false
+	}
+	resolved, err := dotenv.Load(context.TODO(), []dotenv.Source{
+		{Path: base},
+		{Path: filepath.Join(dir, "missing.env"), Optional: true},
+		{Path: override},
+	}, dotenv.ContextLookup(lookup))
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, map[string]string{
+		"HOST": "db",
+		"PORT": "5432",
+		"USER": "admin",
+		"URL":  "postgres://admin@db:5432",
+	}, resolved.Map())
+
+	var names []string
+	for _, v := range resolved.Variables() {
+		names = append(names, v.Name)
+	}
+	assert.DeepEqual(t, []string{"HOST", "PORT", "USER", "URL"}, names)
+
+	host, ok := resolved.Lookup("HOST")
+	assert.Assert(t, ok)
+	assert.Equal(t, host.Location, dotenv.Location(override+":1"))
+
+	url, ok := resolved.Lookup("URL")
+	assert.Assert(t, ok)
+	assert.DeepEqual(t, map[string]dotenv.Location{
+		"USER": dotenv.Location(base + ":3"),
+		"HOST": dotenv.Location(override + ":1"),
+		"PORT": dotenv.Location(base + ":2"),
+	}, url.Expanded)
+
+	assert.Equal(t, resolved.Get("UNDEFINED"), "")
+}
+
+func TestLoadWithOverrides(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.env")
+	override := filepath.Join(dir, "override.env")
+	assert.NilError(t, os.WriteFile(base, []byte("HOST=filehost\nURL=http://$HOST\n"), 0o600))
+	assert.NilError(t, os.WriteFile(override, []byte("HOST=other\nDSN=db://${HOST}\n"), 0o600))
+
+	resolved, err := dotenv.Load(context.TODO(), []dotenv.Source{{Path: base}, {Path: override}}, nil, dotenv.WithOverrides(
+		dotenv.Variable{Name: "HOST", Value: "$oshost", Location: ":os"},
+		dotenv.Variable{Name: "PORT", Value: "80", Location: ":os"},
+	))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[string]string{
+		"HOST": "$oshost",
+		"URL":  "http://$oshost",
+		"DSN":  "db://$oshost",
+		"PORT": "80",
+	}, resolved.Map())
+
+	host, _ := resolved.Lookup("HOST")
+	assert.Equal(t, host.Location, dotenv.Location(":os"))
+	dsn, _ := resolved.Lookup("DSN")
+	assert.DeepEqual(t, dsn.Expanded, map[string]dotenv.Location{"HOST": ":os"})
+
+	// Overrides are loaded without files
+	resolved, err = dotenv.Load(context.TODO(), []dotenv.Source{{Path: filepath.Join(dir, "missing.env"), Optional: true}}, nil,
+		dotenv.WithOverrides(dotenv.Variable{Name: "PORT", Value: "80", Location: ":os"}))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[string]string{"PORT": "80"}, resolved.Map())
+}
+
+func TestLoadMissingRequiredFile(t *testing.T) {
+	_, err := dotenv.Load(context.TODO(), []dotenv.Source{
+		{Path: filepath.Join(t.TempDir(), "missing.env")},
+	}, nil)
+	assert.Assert(t, errors.Is(err, fs.ErrNotExist))
+}
+
+func TestLoadContextLookup(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.env")
+	override := filepath.Join(dir, "override.env")
+	assert.NilError(t, os.WriteFile(base, []byte("USER=admin\n"), 0o600))
+	assert.NilError(t, os.WriteFile(override, []byte("DSN=${USER}:${PASSWORD:-changeme}@db\n"), 0o600))
+	sources := []dotenv.Source{{Path: base}, {Path: override}}
+
+	addr := serveSecrets(t, 0, map[string]string{"PASSWORD": "s3cr3t"})
+	resolved, err := dotenv.Load(context.TODO(), sources, socketLookup(addr))
+	assert.NilError(t, err)
+	assert.Equal(t, resolved.Get("DSN"), "admin:s3cr3t@db")
+
+	// A failing lookup is reported instead of falling back to the default value
+	denied := errors.New("permission denied")
+	_, err = dotenv.Load(context.TODO(), sources, func(context.Context, string) (dotenv.Variable, bool, error) {
+		return dotenv.Variable{}, false, denied
+	})
+	assert.Assert(t, errors.Is(err, denied))
+	assert.ErrorContains(t, err, "DSN: lookup of PASSWORD: permission denied")
+
+	slow := serveSecrets(t, time.Minute, map[string]string{"PASSWORD": "s3cr3t"})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = dotenv.Load(ctx, sources, socketLookup(slow))
+	assert.Assert(t, errors.Is(err, context.DeadlineExceeded), err)
+	assert.Assert(t, time.Since(start) < 10*time.Second)
+}
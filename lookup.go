@@ -1,6 +1,7 @@
 package dotenv
 
 import (
+	"context"
 	"os"
 	"sort"
 )
@@ -8,6 +9,21 @@ import (
 // LookupFn is a function that looks up a variable by name and returns the Variable and whether it was found
 type LookupFn func(string) (Variable, bool)
 
+// ContextLookupFn is a LookupFn that can be cancelled or time-limited through ctx, and can fail.
+// Lookups backed by slow sources should return ctx.Err() once ctx is done
+type ContextLookupFn func(ctx context.Context, name string) (Variable, bool, error)
+
+// ContextLookup adapts a LookupFn to the ContextLookupFn signature. A nil LookupFn gives a nil ContextLookupFn
+func ContextLookup(lookup LookupFn) ContextLookupFn {
+	if lookup == nil {
+		return nil
+	}
+	return func(_ context.Context, name string) (Variable, bool, error) {
+		v, ok := lookup(name)
+		return v, ok, nil
+	}
+}
+
 // CompositeLookup manages multiple LookupFn with explicit priorities
 type CompositeLookup struct {
 	lookups []prioritizedLookup
@@ -20,6 +36,14 @@ func WithPriority(Lookup LookupFn, priority int) prioritizedLookup {
 	}
 }
 
+// WithContextPriority is like WithPriority for a lookup function that can fail
+func WithContextPriority(lookup ContextLookupFn, priority int) prioritizedLookup {
+	return prioritizedLookup{
+		ContextLookup: lookup,
+		Priority:      priority,
+	}
+}
+
 // NewCompositeLookup creates a new CompositeLookup with the given prioritized lookup functions
 // Higher priority values are tried first
 func NewCompositeLookup(lookups ...prioritizedLookup) *CompositeLookup {
@@ -36,18 +60,38 @@ func NewCompositeLookup(lookups ...prioritizedLookup) *CompositeLookup {
 
 // PrioritizedLookup associates a lookup function with a priority value
 type prioritizedLookup struct {
-	Priority int
-	Lookup   LookupFn
+	Priority      int
+	Lookup        LookupFn
+	ContextLookup ContextLookupFn
 }
 
-// Lookup implements the LookupFn signature by trying each lookup function in priority order
+// Lookup implements the LookupFn signature by trying each lookup function in priority order.
+// A failing lookup function stops the lookup instead of falling through to lower priorities.
+//
+// Deprecated: Lookup can't report the error of a failing lookup function, which callers see as an unset variable
+// and may replace with a default value. Use LookupContext instead
 func (c *CompositeLookup) Lookup(name string) (Variable, bool) {
+	v, ok, _ := c.LookupContext(context.Background(), name)
+	return v, ok
+}
+
+// LookupContext implements the ContextLookupFn signature by trying each lookup function in priority order,
+// and returns the error of the first failing lookup function
+func (c *CompositeLookup) LookupContext(ctx context.Context, name string) (Variable, bool, error) {
 	for _, pl := range c.lookups {
-		if v, ok := pl.Lookup(name); ok {
-			return v, true
+		if pl.ContextLookup != nil {
+			v, ok, err := pl.ContextLookup(ctx, name)
+			if err != nil {
+				return Variable{}, false, err
+			}
+			if ok {
+				return v, true, nil
+			}
+		} else if v, ok := pl.Lookup(name); ok {
+			return v, true, nil
 		}
 	}
-	return Variable{}, false
+	return Variable{}, false, nil
 }
 
 // Lookup implements the LookupFn signature by looking up variables in the OS environment
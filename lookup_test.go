@@ -0,0 +1,193 @@
+package dotenv_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/compose-spec/dotenv"
+	"gotest.tools/v3/assert"
+)
+
+// socketLookup looks up variables from a line based secret server, honoring ctx
+func socketLookup(addr string) dotenv.ContextLookupFn {
+	return func(ctx context.Context, name string) (dotenv.Variable, bool, error) {
+		conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return dotenv.Variable{}, false, err
+		}
+		defer conn.Close()
+		stop := context.AfterFunc(ctx, func() {
+			_ = conn.Close()
+		})
+		defer stop()
+
+		if _, err := fmt.Fprintln(conn, name); err != nil {
+			return dotenv.Variable{}, false, err
+		}
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil {
+			if ctx.Err() != nil {
+				return dotenv.Variable{}, false, ctx.Err()
+			}
+			return dotenv.Variable{}, false, err
+		}
+		value, ok := strings.CutPrefix(strings.TrimSuffix(line, "\n"), "=")
+		return dotenv.Variable{Name: name, Value: value, Location: "secrets"}, ok, nil
+	}
+}
+
+// serveSecrets answers lookups of the secret server after delay
+func serveSecrets(t *testing.T, delay time.Duration, secrets map[string]string) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				name, err := bufio.NewReader(conn).ReadString('\n')
+				if err != nil {
+					return
+				}
+				time.Sleep(delay)
+				if value, ok := secrets[strings.TrimSuffix(name, "\n")]; ok {
+					fmt.Fprintf(conn, "=%s\n", value)
+				} else {
+					fmt.Fprintln(conn)
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestResolveContext(t *testing.T) {
+	addr := serveSecrets(t, 0, map[string]string{"PASSWORD": "s3cr3t"})
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader("USER=admin\nDSN=${USER}:${PASSWORD}@db\nMISSING=${UNSET:-none}"))
+	assert.NilError(t, err)
+
+	resolved, err := env.ResolveEnv(context.TODO(), socketLookup(addr))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[string]string{
+		"USER":    "admin",
+		"DSN":     "admin:s3cr3t@db",
+		"MISSING": "none",
+	}, resolved.Map())
+	dsn, _ := resolved.Lookup("DSN")
+	assert.Equal(t, dsn.Expanded["PASSWORD"], dotenv.Location("secrets"))
+}
+
+func TestResolveContextDeadline(t *testing.T) {
+	addr := serveSecrets(t, time.Minute, map[string]string{"PASSWORD": "s3cr3t"})
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader("DSN=admin:${PASSWORD}@db"))
+	assert.NilError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = env.ResolveContext(ctx, socketLookup(addr))
+	assert.Assert(t, errors.Is(err, context.DeadlineExceeded), err)
+	assert.Assert(t, time.Since(start) < 10*time.Second)
+}
+
+func TestResolveContextCancelled(t *testing.T) {
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader("FOO=$BAR"))
+	assert.NilError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = env.ResolveContext(ctx, func(context.Context, string) (dotenv.Variable, bool, error) {
+		t.Fatal("lookup must not be called once the context is done")
+		return dotenv.Variable{}, false, nil
+	})
+	assert.Assert(t, errors.Is(err, context.Canceled))
+}
+
+func TestContextLookup(t *testing.T) {
+	lookup := dotenv.ContextLookup(func(name string) (dotenv.Variable, bool) {
+		return dotenv.Variable{Name: name, Value: "value"}, name == "FOO"
+	})
+	v, ok, err := lookup(context.TODO(), "FOO")
+	assert.NilError(t, err)
+	assert.Assert(t, ok)
+	assert.Equal(t, v.Value, "value")
+
+	_, ok, err = lookup(context.TODO(), "BAR")
+	assert.NilError(t, err)
+	assert.Assert(t, !ok)
+
+	assert.Assert(t, dotenv.ContextLookup(nil) == nil)
+}
+
+func TestResolveContextLookupError(t *testing.T) {
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader("USER=admin\nDSN=${USER}:${PASSWORD:-changeme}@db"), dotenv.WithSourceName(".env"))
+	assert.NilError(t, err)
+
+	denied := errors.New("permission denied")
+	secrets := func(_ context.Context, name string) (dotenv.Variable, bool, error) {
+		return dotenv.Variable{}, false, denied
+	}
+	_, err = env.ResolveContext(context.TODO(), secrets)
+	assert.Error(t, err, ".env:2: DSN: lookup of PASSWORD: permission denied")
+	assert.Assert(t, errors.Is(err, denied))
+	var expansionErr *dotenv.ExpansionError
+	assert.Assert(t, errors.As(err, &expansionErr))
+	assert.Equal(t, expansionErr.Kind, dotenv.LookupFailed)
+	assert.Equal(t, expansionErr.Location, dotenv.Location(".env:2"))
+}
+
+func TestCompositeLookupContext(t *testing.T) {
+	denied := errors.New("permission denied")
+	var fallbackCalls int
+	composite := dotenv.NewCompositeLookup(
+		dotenv.WithPriority(func(name string) (dotenv.Variable, bool) {
+			fallbackCalls++
+			return dotenv.Variable{Name: name, Value: "fallback"}, true
+		}, 0),
+		dotenv.WithContextPriority(func(_ context.Context, name string) (dotenv.Variable, bool, error) {
+			switch name {
+			case "SECRET":
+				return dotenv.Variable{Name: name, Value: "s3cr3t"}, true, nil
+			case "DENIED":
+				return dotenv.Variable{}, false, denied
+			}
+			return dotenv.Variable{}, false, nil
+		}, 1),
+	)
+
+	v, ok, err := composite.LookupContext(context.TODO(), "SECRET")
+	assert.NilError(t, err)
+	assert.Assert(t, ok)
+	assert.Equal(t, v.Value, "s3cr3t")
+
+	v, ok, err = composite.LookupContext(context.TODO(), "OTHER")
+	assert.NilError(t, err)
+	assert.Assert(t, ok)
+	assert.Equal(t, v.Value, "fallback")
+	assert.Equal(t, fallbackCalls, 1)
+
+	_, ok, err = composite.LookupContext(context.TODO(), "DENIED")
+	assert.Assert(t, errors.Is(err, denied))
+	assert.Assert(t, !ok)
+
+	// A failing lookup doesn't fall through to lower priorities
+	assert.Equal(t, fallbackCalls, 1)
+
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader("A=$SECRET\nB=${DENIED:-default}"))
+	assert.NilError(t, err)
+	_, err = env.ResolveContext(context.TODO(), composite.LookupContext)
+	assert.Error(t, err, "line 2: B: lookup of DENIED: permission denied")
+}
@@ -0,0 +1,25 @@
+package dotenv
+
+import (
+	"bytes"
+	"maps"
+	"slices"
+)
+
+// Marshal returns the .env representation of values, with variables sorted by name.
+// Each value is written using the simplest quoting that parses back to the exact same value, without expansion
+func Marshal(values map[string]string) ([]byte, error) {
+	envFile := &EnvFile{}
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		var err error
+		if envFile, err = envFile.Set(name, values[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := envFile.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
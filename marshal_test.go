@@ -0,0 +1,108 @@
+package dotenv_test
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/compose-spec/dotenv"
+	"gotest.tools/v3/assert"
+)
+
+func TestMarshal(t *testing.T) {
+	out, err := dotenv.Marshal(map[string]string{
+		"PLAIN":     "value",
+		"EMPTY":     "",
+		"SPACES":    "  padded  ",
+		"COMMENT":   "a # b",
+		"DOLLAR":    "$HOME",
+		"QUOTES":    `it's "quoted"`,
+		"MULTILINE": "line1\nline2",
+		"BACKSLASH": `C:\path\`,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), strings.Join([]string{
+		`BACKSLASH=C:\path\`,
+		`COMMENT='a # b'`,
+		`DOLLAR='$HOME'`,
+		`EMPTY=`,
+		`MULTILINE="line1\nline2"`,
+		`PLAIN=value`,
+		`QUOTES=it's "quoted"`,
+		`SPACES='  padded  '`,
+		``,
+	}, "\n"))
+}
+
+func TestMarshalInvalidName(t *testing.T) {
+	_, err := dotenv.Marshal(map[string]string{"1FOO": "bar"})
+	assert.Error(t, err, "invalid variable name \"1FOO\"")
+}
+
+// value generates strings made of characters which are significant to the .env syntax
+type value string
+
+func (value) Generate(r *rand.Rand, size int) reflect.Value {
+	chars := []string{"a", "Z", "0", " ", "\t", "\u00a0", "#", "'", `"`, "$", "{", "}", ":", "=", "-", `\`, "\n", "\r", "é"}
+	var s strings.Builder
+	for range r.Intn(size + 1) {
+		s.WriteString(chars[r.Intn(len(chars))])
+	}
+	return reflect.ValueOf(value(s.String()))
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	roundTrip := func(a, b, c value) bool {
+		values := map[string]string{"A": string(a), "B": string(b), "C": string(c)}
+		out, err := dotenv.Marshal(values)
+		if err != nil {
+			t.Log(err)
+			return false
+		}
+		env, err := dotenv.Parse(context.TODO(), bytes.NewReader(out))
+		if err != nil {
+			t.Log(err)
+			return false
+		}
+		resolved, err := env.Resolve(nil)
+		if err != nil {
+			t.Log(err)
+			return false
+		}
+		return reflect.DeepEqual(values, resolved)
+	}
+	assert.NilError(t, quick.Check(roundTrip, &quick.Config{MaxCount: 2000}))
+}
+
+func TestWriteToRoundTrip(t *testing.T) {
+	roundTrip := func(a, b value, styleA, styleB uint8) bool {
+		env := &dotenv.EnvFile{
+			Variables: []dotenv.Variable{
+				{Name: "A", RawValue: string(a), Quoted: dotenv.QuoteStyle(styleA % 3)},
+				{Name: "B", RawValue: string(b), Quoted: dotenv.QuoteStyle(styleB % 3)},
+			},
+		}
+		expected, expectedErr := env.Resolve(nil)
+
+		var out bytes.Buffer
+		if _, err := env.WriteTo(&out); err != nil {
+			t.Log(err)
+			return false
+		}
+		reparsed, err := dotenv.Parse(context.TODO(), &out)
+		if err != nil {
+			t.Log(err)
+			return false
+		}
+		resolved, err := reparsed.Resolve(nil)
+		if expectedErr != nil || err != nil {
+			return expectedErr != nil && err != nil && expectedErr.Error() == err.Error()
+		}
+		return reflect.DeepEqual(expected, resolved)
+	}
+	assert.NilError(t, quick.Check(roundTrip, &quick.Config{MaxCount: 2000}))
+}
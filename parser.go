@@ -1,13 +1,30 @@
 package dotenv
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"os"
 	"strings"
+	"unicode"
 )
 
+// ParseOption configures the behavior of Parse
+type ParseOption func(*parseOptions)
+
+type parseOptions struct {
+	sourceName string
+	recover    bool
+}
+
+// WithSourceName sets the name of the parsed source, typically a file path, used to build variable locations
+func WithSourceName(name string) ParseOption {
+	return func(o *parseOptions) {
+		o.sourceName = name
+	}
+}
+
 // unescapeDoubleQuoted processes escape sequences in a double-quoted string
 func unescapeDoubleQuoted(s string) string {
 	var result strings.Builder
@@ -44,18 +61,62 @@ func unescapeDoubleQuoted(s string) string {
 	return result.String()
 }
 
+// WithErrorRecovery makes Parse skip invalid lines instead of stopping at the first error.
+// Parse then returns the partial EnvFile along with all errors joined by errors.Join
+func WithErrorRecovery() ParseOption {
+	return func(o *parseOptions) {
+		o.recover = true
+	}
+}
+
+// ParseFile opens the .env file at path and parses it, using path as the source name of variable locations
+func ParseFile(ctx context.Context, path string, opts ...ParseOption) (*EnvFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Parse(ctx, f, append([]ParseOption{WithSourceName(path)}, opts...)...)
+}
+
 // Parse reads an .env file from the provided reader and returns a parsed EnvFile
-func Parse(ctx context.Context, reader io.Reader) (*EnvFile, error) {
+func Parse(ctx context.Context, reader io.Reader, opts ...ParseOption) (*EnvFile, error) {
+	var options parseOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	envFile := &EnvFile{
 		Variables: []Variable{},
+		Nodes:     []Node{},
 	}
 
-	scanner := bufio.NewScanner(reader)
+	lines := newLineReader(reader)
 	lineNumber := 0
 	// Track defined variable names
 	definedVars := make(map[string]bool)
 
-	for scanner.Scan() {
+	var errs []error
+	// report records a parse error, which aborts parsing unless error recovery is enabled.
+	// When recovering, the offending text is kept in the syntax tree as an invalid node
+	report := func(err *ParseError, text, eol string) error {
+		if !options.recover {
+			return err
+		}
+		errs = append(errs, err)
+		envFile.Nodes = append(envFile.Nodes, Node{Kind: InvalidNode, Text: text, EOL: eol})
+		return nil
+	}
+
+	for {
+		line, eol, err := lines.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
 		lineNumber++
 
 		// Check context cancellation
@@ -65,11 +126,15 @@ func Parse(ctx context.Context, reader io.Reader) (*EnvFile, error) {
 		default:
 		}
 
-		line := scanner.Text()
 		originalLine := line
 
-		// Skip empty lines and comments
-		if line == "" || strings.HasPrefix(line, "#") {
+		// Keep empty lines and comments in the syntax tree only
+		if line == "" {
+			envFile.Nodes = append(envFile.Nodes, Node{Kind: BlankNode, EOL: eol})
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			envFile.Nodes = append(envFile.Nodes, Node{Kind: CommentNode, Text: line, EOL: eol})
 			continue
 		}
 
@@ -90,12 +155,31 @@ func Parse(ctx context.Context, reader io.Reader) (*EnvFile, error) {
 			if isExportLine {
 				varName := strings.TrimSpace(line)
 				if definedVars[varName] {
-					// Valid export of existing variable, skip line
+					// Valid export of existing variable, only kept in the syntax tree
+					envFile.Nodes = append(envFile.Nodes, Node{Kind: ExportNode, Text: originalLine, Name: varName, EOL: eol})
 					continue
 				}
-				return nil, fmt.Errorf("line %d %q has an unset variable", lineNumber, varName)
+				if err := report(&ParseError{
+					Kind:    UnsetExport,
+					File:    options.sourceName,
+					Line:    lineNumber,
+					Column:  column(originalLine, line),
+					Snippet: varName,
+				}, originalLine, eol); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if err := report(&ParseError{
+				Kind:    MissingSeparator,
+				File:    options.sourceName,
+				Line:    lineNumber,
+				Column:  column(originalLine, originalLine),
+				Snippet: originalLine,
+			}, originalLine, eol); err != nil {
+				return nil, err
 			}
-			return nil, fmt.Errorf("line %d: no separator found in line: %s", lineNumber, originalLine)
+			continue
 		} else if equalIdx == -1 {
 			separatorIdx = colonIdx
 		} else if colonIdx == -1 {
@@ -115,18 +199,41 @@ func Parse(ctx context.Context, reader io.Reader) (*EnvFile, error) {
 
 		// Validate variable name - must match [A-Za-z0-9_.-]
 		if !isValidVariableName(name) {
-			return nil, fmt.Errorf("line %d: invalid variable name %q", lineNumber, name)
+			if err := report(&ParseError{
+				Kind:    InvalidName,
+				File:    options.sourceName,
+				Line:    lineNumber,
+				Column:  column(originalLine, line),
+				Snippet: name,
+			}, originalLine, eol); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// Record the layout of the assignment in the syntax tree
+		node := Node{
+			Kind:        VariableNode,
+			Export:      originalLine[:len(originalLine)-len(line)],
+			Indent:      leadingSpace(line[:separatorIdx]),
+			Name:        name,
+			SpaceBefore: trailingSpace(line[:separatorIdx]),
+			Separator:   line[separatorIdx : separatorIdx+1],
+			SpaceAfter:  leadingSpace(line[separatorIdx+1:]),
 		}
+		// valueStart is the offset of the value in the original line
+		valueStart := len(originalLine) - len(line) + separatorIdx + 1 + len(node.SpaceAfter)
 
 		// Handle inline comments: strip # comment from unquoted values
 		// But preserve # in quoted values
-		quoteStyle := Unquoted
 		if len(value) > 0 && value[0] != '"' && value[0] != '\'' {
 			// Unquoted value: look for # comment marker
 			if commentIdx := strings.Index(value, "#"); commentIdx != -1 {
 				value = strings.TrimSpace(value[:commentIdx])
 			}
 		}
+		node.Value = value
+		node.Trailing = originalLine[valueStart+len(value):]
 
 		// Handle multi-line quoted values
 		if len(value) > 0 && (value[0] == '"' || value[0] == '\'') {
@@ -136,7 +243,7 @@ func Parse(ctx context.Context, reader io.Reader) (*EnvFile, error) {
 			for i := 1; i < len(value); i++ {
 				if value[i] == quoteChar {
 					// Check if it's escaped (for double quotes)
-					if quoteChar == '"' && i > 0 && value[i-1] == '\\' {
+					if quoteChar == '"' && isEscaped(value, i) {
 						continue
 					}
 					closingQuoteIdx = i
@@ -146,20 +253,35 @@ func Parse(ctx context.Context, reader io.Reader) (*EnvFile, error) {
 
 			// If quote is not closed, read more lines
 			if closingQuoteIdx == -1 {
+				startLine := lineNumber
+				quoteColumn := column(originalLine, line[separatorIdx+1:])
+
 				var multilineValue strings.Builder
 				multilineValue.WriteString(value)
+				// rawText keeps the lines as written, including their terminators
+				var rawText strings.Builder
+				rawText.WriteString(originalLine)
 
-				for scanner.Scan() {
+				for {
+					nextLine, nextEOL, err := lines.next()
+					if err == io.EOF {
+						break
+					}
+					if err != nil {
+						return nil, err
+					}
 					lineNumber++
-					nextLine := scanner.Text()
 					multilineValue.WriteString("\n")
 					multilineValue.WriteString(nextLine)
+					rawText.WriteString(eol)
+					rawText.WriteString(nextLine)
+					eol = nextEOL
 
 					// Look for closing quote in this line
 					for i := 0; i < len(nextLine); i++ {
 						if nextLine[i] == quoteChar {
 							// Check if it's escaped (for double quotes)
-							if quoteChar == '"' && i > 0 && nextLine[i-1] == '\\' {
+							if quoteChar == '"' && isEscaped(nextLine, i) {
 								continue
 							}
 							closingQuoteIdx = i
@@ -172,40 +294,82 @@ func Parse(ctx context.Context, reader io.Reader) (*EnvFile, error) {
 					}
 				}
 
+				if closingQuoteIdx == -1 {
+					if err := report(&ParseError{
+						Kind:    UnterminatedQuote,
+						File:    options.sourceName,
+						Line:    startLine,
+						Column:  quoteColumn,
+						Snippet: originalLine,
+					}, rawText.String(), eol); err != nil {
+						return nil, err
+					}
+					continue
+				}
+
 				value = multilineValue.String()
+				node.Value = rawText.String()[valueStart:]
+				node.Trailing = ""
 			}
 		}
+		node.EOL = eol
 
 		// Track quote style and remove surrounding quotes if present
-		if len(value) >= 2 {
-			if value[0] == '"' && value[len(value)-1] == '"' {
-				// Double-quoted: remove quotes and process escape sequences
-				quoteStyle = DoubleQuoted
-				value = unescapeDoubleQuoted(value[1 : len(value)-1])
-			} else if value[0] == '\'' && value[len(value)-1] == '\'' {
-				// Single-quoted: just remove quotes, no escape processing
-				quoteStyle = Quoted
-				value = value[1 : len(value)-1]
-			}
-		}
+		value, quoteStyle := unquote(value)
 
 		variable := Variable{
 			Name:     name,
 			RawValue: value,
-			Location: Location(fmt.Sprintf(":%d", lineNumber)),
+			Location: Location(fmt.Sprintf("%s:%d", options.sourceName, lineNumber)),
 			Quoted:   quoteStyle,
 			Expanded: make(map[string]Location),
 		}
 
 		envFile.Variables = append(envFile.Variables, variable)
+		envFile.Nodes = append(envFile.Nodes, node)
 		definedVars[name] = true
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	return envFile, errors.Join(errs...)
+}
+
+// unquote removes the quotes surrounding a value and returns its quote style.
+// Escape sequences of double-quoted values are processed
+func unquote(value string) (string, QuoteStyle) {
+	if len(value) >= 2 {
+		if value[0] == '"' && value[len(value)-1] == '"' {
+			// Double-quoted: remove quotes and process escape sequences
+			return unescapeDoubleQuoted(value[1 : len(value)-1]), DoubleQuoted
+		} else if value[0] == '\'' && value[len(value)-1] == '\'' {
+			// Single-quoted: just remove quotes, no escape processing
+			return value[1 : len(value)-1], Quoted
+		}
 	}
+	return value, Unquoted
+}
+
+// isEscaped returns true if the character at index i of s is preceded by an odd number of backslashes
+func isEscaped(s string, i int) bool {
+	backslashes := 0
+	for j := i - 1; j >= 0 && s[j] == '\\'; j-- {
+		backslashes++
+	}
+	return backslashes%2 == 1
+}
+
+// leadingSpace returns the whitespace s starts with
+func leadingSpace(s string) string {
+	return s[:len(s)-len(strings.TrimLeftFunc(s, unicode.IsSpace))]
+}
+
+// trailingSpace returns the whitespace s ends with
+func trailingSpace(s string) string {
+	return s[len(strings.TrimRightFunc(s, unicode.IsSpace)):]
+}
 
-	return envFile, nil
+// column returns the 1-based column in line of the first non-space character of rest, which must be a suffix of line
+func column(line, rest string) int {
+	return len(line) - len(strings.TrimLeftFunc(rest, unicode.IsSpace)) + 1
 }
 
 // isValidVariableName returns true if the variable name matches [A-Za-z0-9_.-] and doesn't start with a digit
@@ -2,6 +2,10 @@ package dotenv_test
 
 import (
 	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -251,6 +255,29 @@ func TestParse(t *testing.T) {
 				"FOO": `bar\baz`,
 			},
 		},
+		{
+			name:  "double quoted ending with escaped backslash",
+			input: "FOO=\"C:\\\\\"\nBAR=b",
+			expect: map[string]string{
+				"FOO": `C:\`,
+				"BAR": "b",
+			},
+		},
+		{
+			name:  "double quoted with escaped backslash before escaped quote",
+			input: `FOO="a\\\"b"`,
+			expect: map[string]string{
+				"FOO": `a\"b`,
+			},
+		},
+		{
+			name:  "double quoted multi-line value ending with escaped backslash",
+			input: "FOO=\"line1\nline2\\\\\"\nBAR=b",
+			expect: map[string]string{
+				"FOO": "line1\nline2\\",
+				"BAR": "b",
+			},
+		},
 		{
 			name:  "double quoted with newline",
 			input: `FOO="bar\nbaz"`,
@@ -493,27 +520,27 @@ func TestParse(t *testing.T) {
 		{
 			name:  "required variable with question mark unset variable",
 			input: "FOO=${UNSET?UNSET is required}",
-			err:   "UNSET is required",
+			err:   "line 1: UNSET is required",
 		},
 		{
 			name:  "required variable with question mark unset variable no message",
 			input: "FOO=${UNSET?}",
-			err:   "UNSET: required variable is not set",
+			err:   "line 1: UNSET: required variable is not set",
 		},
 		{
 			name:  "required variable with colon question mark unset variable",
 			input: "FOO=${UNSET:?UNSET is required}",
-			err:   "UNSET is required",
+			err:   "line 1: UNSET is required",
 		},
 		{
 			name:  "required variable with colon question mark empty variable",
 			input: "BAR=\nFOO=${BAR:?BAR is required}",
-			err:   "BAR is required",
+			err:   "line 2: BAR is required",
 		},
 		{
 			name:  "required variable with colon question mark unset variable no message",
 			input: "FOO=${UNSET:?}",
-			err:   "UNSET: required variable is not set",
+			err:   "line 1: UNSET: required variable is not set",
 		},
 		{
 			name:  "nested expansion in default value with dash",
@@ -601,7 +628,7 @@ func TestParse(t *testing.T) {
 		{
 			name:  "default value with error expanded when variable is unset",
 			input: "FOO=${VAR-${BAR?BAR is required}}",
-			err:   "BAR is required",
+			err:   "line 1: BAR is required",
 		},
 		{
 			name:  "default value not expanded when variable is set with colon dash",
@@ -614,7 +641,7 @@ func TestParse(t *testing.T) {
 		{
 			name:  "default value with error expanded when variable is empty with colon dash",
 			input: "VAR=\nFOO=${VAR:-${BAR?BAR is required}}",
-			err:   "BAR is required",
+			err:   "line 2: BAR is required",
 		},
 		{
 			name:  "line without separator",
@@ -678,3 +705,38 @@ func TestParse(t *testing.T) {
 		})
 	}
 }
+
+func TestParseFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.env")
+	err := os.WriteFile(path, []byte("# base\nBASE=/usr\nPATH=${BASE}/bin\n"), 0o600)
+	assert.NilError(t, err)
+
+	env, err := dotenv.ParseFile(context.TODO(), path)
+	assert.NilError(t, err)
+	resolved, err := env.ResolveEnv(context.TODO(), nil)
+	assert.NilError(t, err)
+
+	assert.Equal(t, len(env.Variables), 2)
+	assert.Equal(t, env.Variables[0].Location, dotenv.Location(path+":2"))
+	assert.Equal(t, env.Variables[1].Location, dotenv.Location(path+":3"))
+	v, ok := resolved.Lookup("PATH")
+	assert.Assert(t, ok)
+	assert.DeepEqual(t, v.Expanded, map[string]dotenv.Location{
+		"BASE": dotenv.Location(path + ":2"),
+	})
+}
+
+func TestParseWithSourceName(t *testing.T) {
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader("FOO=bar"), dotenv.WithSourceName("app.env"))
+	assert.NilError(t, err)
+	assert.Equal(t, env.Variables[0].Location, dotenv.Location("app.env:1"))
+
+	env, err = dotenv.Parse(context.TODO(), strings.NewReader("FOO=bar"))
+	assert.NilError(t, err)
+	assert.Equal(t, env.Variables[0].Location, dotenv.Location(":1"))
+}
+
+func TestParseFileNotFound(t *testing.T) {
+	_, err := dotenv.ParseFile(context.TODO(), filepath.Join(t.TempDir(), "missing.env"))
+	assert.Assert(t, errors.Is(err, fs.ErrNotExist))
+}
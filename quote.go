@@ -0,0 +1,93 @@
+package dotenv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// quoteValue returns the source text of a value whose raw value, as found in Variable.RawValue,
+// is raw, using the given quote style. It fails if the quote style can't represent the value
+func quoteValue(raw string, style QuoteStyle) (string, error) {
+	switch style {
+	case Unquoted:
+		if !canBeUnquoted(raw) {
+			return "", fmt.Errorf("value %q can't be written unquoted", raw)
+		}
+		return raw, nil
+	case Quoted:
+		if !canBeSingleQuoted(raw) {
+			return "", fmt.Errorf("value %q can't be written single-quoted", raw)
+		}
+		return "'" + raw + "'", nil
+	case DoubleQuoted:
+		return `"` + escapeDoubleQuoted(raw) + `"`, nil
+	default:
+		return "", fmt.Errorf("unknown quote style %d", style)
+	}
+}
+
+// canBeUnquoted returns true if raw is read back unchanged when written without quotes
+func canBeUnquoted(raw string) bool {
+	if raw != strings.TrimSpace(raw) || strings.ContainsAny(raw, "#\n\r") {
+		return false
+	}
+	return raw == "" || (raw[0] != '"' && raw[0] != '\'')
+}
+
+// canBeSingleQuoted returns true if raw is read back unchanged when written between single quotes
+func canBeSingleQuoted(raw string) bool {
+	return !strings.ContainsAny(raw, "'\n\r")
+}
+
+// escapeDoubleQuoted is the reverse of unescapeDoubleQuoted
+func escapeDoubleQuoted(s string) string {
+	var result strings.Builder
+	result.Grow(len(s))
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			// A backslash only needs escaping when it would otherwise start an escape sequence
+			if i+1 == len(s) || strings.IndexByte("ntr\\\"\n\r", s[i+1]) != -1 {
+				result.WriteString(`\\`)
+			} else {
+				result.WriteByte('\\')
+			}
+		case '"':
+			result.WriteString(`\"`)
+		case '\n':
+			result.WriteString(`\n`)
+		case '\r':
+			result.WriteString(`\r`)
+		default:
+			result.WriteByte(s[i])
+		}
+	}
+
+	return result.String()
+}
+
+// literalValue returns the raw value and the simplest quote style which resolve to value without any expansion.
+// Unquoted values are preferred, then single-quoted values, then double-quoted values
+func literalValue(value string) (string, QuoteStyle) {
+	if !strings.Contains(value, "$") && canBeUnquoted(value) {
+		return value, Unquoted
+	}
+	if canBeSingleQuoted(value) {
+		return value, Quoted
+	}
+	return escapeDollar(value), DoubleQuoted
+}
+
+// expandableValue returns the simplest quote style which preserves the expansion of raw
+func expandableValue(raw string) QuoteStyle {
+	if canBeUnquoted(raw) {
+		return Unquoted
+	}
+	return DoubleQuoted
+}
+
+// escapeDollar escapes $ signs so that expanding the result yields value
+func escapeDollar(value string) string {
+	return strings.ReplaceAll(value, "$", `\$`)
+}
@@ -0,0 +1,101 @@
+package dotenv
+
+import (
+	"iter"
+	"maps"
+)
+
+// ResolvedEnv holds resolved variables along with their provenance: the Location of the definition that won,
+// and the variables expanded to compute its value.
+// A ResolvedEnv is immutable once returned, and safe to share across goroutines
+type ResolvedEnv struct {
+	variables []Variable
+	index     map[string]int
+}
+
+func newResolvedEnv() *ResolvedEnv {
+	return &ResolvedEnv{index: make(map[string]int)}
+}
+
+// set adds a resolved variable, overriding any previous variable with the same name
+func (r *ResolvedEnv) set(v Variable) {
+	if i, ok := r.index[v.Name]; ok {
+		r.variables[i] = v
+		return
+	}
+	r.index[v.Name] = len(r.variables)
+	r.variables = append(r.variables, v)
+}
+
+// Lookup returns the resolved variable name and whether it is defined.
+// It implements the LookupFn signature
+func (r *ResolvedEnv) Lookup(name string) (Variable, bool) {
+	i, ok := r.index[name]
+	if !ok {
+		return Variable{}, false
+	}
+	return cloneVariable(r.variables[i]), true
+}
+
+// Get returns the resolved value of the variable name, or an empty string if it isn't defined
+func (r *ResolvedEnv) Get(name string) string {
+	v, _ := r.Lookup(name)
+	return v.Value
+}
+
+// Variables returns the resolved variables, in the order they were first defined
+func (r *ResolvedEnv) Variables() []Variable {
+	variables := make([]Variable, len(r.variables))
+	for i, v := range r.variables {
+		variables[i] = cloneVariable(v)
+	}
+	return variables
+}
+
+// All iterates over the names and resolved variables, in the order they were first defined
+func (r *ResolvedEnv) All() iter.Seq2[string, Variable] {
+	return func(yield func(string, Variable) bool) {
+		for _, v := range r.variables {
+			if !yield(v.Name, cloneVariable(v)) {
+				return
+			}
+		}
+	}
+}
+
+// Len returns the number of resolved variables
+func (r *ResolvedEnv) Len() int {
+	return len(r.variables)
+}
+
+// Environ returns the resolved variables as "NAME=value" strings, in the order they were first defined,
+// like os.Environ
+func (r *ResolvedEnv) Environ() []string {
+	environ := make([]string, len(r.variables))
+	for i, v := range r.variables {
+		environ[i] = v.Name + "=" + v.Value
+	}
+	return environ
+}
+
+// Provenance returns the Location of the definition of the variable name, and the locations of the variables
+// expanded to compute its value
+func (r *ResolvedEnv) Provenance(name string) (Location, map[string]Location, bool) {
+	v, ok := r.Lookup(name)
+	return v.Location, v.Expanded, ok
+}
+
+// Map returns the resolved values by variable name
+func (r *ResolvedEnv) Map() map[string]string {
+	values := make(map[string]string, len(r.variables))
+	for _, v := range r.variables {
+		values[v.Name] = v.Value
+	}
+	return values
+}
+
+// cloneVariable returns a copy of v which doesn't share its Expanded map
+func cloneVariable(v Variable) Variable {
+	v.Expanded = maps.Clone(v.Expanded)
+	return v
+}
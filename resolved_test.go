@@ -0,0 +1,135 @@
+package dotenv_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/compose-spec/dotenv"
+	"gotest.tools/v3/assert"
+)
+
+func TestResolveEnvMultipleTimes(t *testing.T) {
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader("URL=https://${DOMAIN}/api\nNAME=app"), dotenv.WithSourceName(".env"))
+	assert.NilError(t, err)
+
+	lookups := map[string]string{
+		"dev":     "localhost",
+		"staging": "staging.example.com",
+		"prod":    "example.com",
+	}
+	for stage, domain := range lookups {
+		lookup := func(_ context.Context, name string) (dotenv.Variable, bool, error) {
+			return dotenv.Variable{Name: name, Value: domain, Location: dotenv.Location(stage)}, name == "DOMAIN", nil
+		}
+		resolved, err := env.ResolveEnv(context.TODO(), lookup)
+		assert.NilError(t, err)
+		url, ok := resolved.Lookup("URL")
+		assert.Assert(t, ok)
+		assert.Equal(t, url.Value, "https://"+domain+"/api")
+		assert.DeepEqual(t, url.Expanded, map[string]dotenv.Location{"DOMAIN": dotenv.Location(stage)})
+		assert.Equal(t, url.Location, dotenv.Location(".env:1"))
+
+		// Resolve must not return values computed with a previous lookup
+		vars, err := env.Resolve(func(name string) (dotenv.Variable, bool) {
+			v, ok, _ := lookup(context.TODO(), name)
+			return v, ok
+		})
+		assert.NilError(t, err)
+		assert.Equal(t, vars["URL"], "https://"+domain+"/api")
+	}
+
+	// The parsed file isn't modified by resolution
+	assert.Equal(t, env.Variables[0].Value, "")
+	assert.Equal(t, env.Variables[0].RawValue, "https://${DOMAIN}/api")
+}
+
+func TestResolvedEnv(t *testing.T) {
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader("HOST=localhost\nPORT=80\nURL=http://$HOST:$PORT\nHOST=db"), dotenv.WithSourceName(".env"))
+	assert.NilError(t, err)
+	resolved, err := env.ResolveEnv(context.TODO(), nil)
+	assert.NilError(t, err)
+
+	assert.Equal(t, resolved.Len(), 3)
+	assert.DeepEqual(t, resolved.Environ(), []string{"HOST=db", "PORT=80", "URL=http://localhost:80"})
+
+	var names []string
+	for name, v := range resolved.All() {
+		names = append(names, name)
+		assert.Equal(t, v.Value, resolved.Get(name))
+		if name == "PORT" {
+			break
+		}
+	}
+	assert.DeepEqual(t, names, []string{"HOST", "PORT"})
+
+	location, expanded, ok := resolved.Provenance("URL")
+	assert.Assert(t, ok)
+	assert.Equal(t, location, dotenv.Location(".env:3"))
+	assert.DeepEqual(t, expanded, map[string]dotenv.Location{"HOST": ".env:1", "PORT": ".env:2"})
+
+	// Variables returned by the snapshot can't alter it
+	expanded["HOST"] = "altered"
+	v, _ := resolved.Lookup("URL")
+	v.Value = "altered"
+	assert.Equal(t, resolved.Get("URL"), "http://localhost:80")
+	_, expanded, _ = resolved.Provenance("URL")
+	assert.Equal(t, expanded["HOST"], dotenv.Location(".env:1"))
+
+	_, _, ok = resolved.Provenance("UNDEFINED")
+	assert.Assert(t, !ok)
+}
+
+// TestConcurrentResolve is meant to be run with the race detector, as make test does
+func TestConcurrentResolve(t *testing.T) {
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader("HOST=localhost\nURL=http://$HOST:${PORT:-80}\n"))
+	assert.NilError(t, err)
+	resolved, err := env.ResolveEnv(context.TODO(), nil)
+	assert.NilError(t, err)
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			port := fmt.Sprint(8000 + i)
+			lookup := func(_ context.Context, name string) (dotenv.Variable, bool, error) {
+				return dotenv.Variable{Name: name, Value: port}, name == "PORT", nil
+			}
+			for range 50 {
+				r, err := env.ResolveEnv(context.TODO(), lookup)
+				assert.Check(t, err)
+				if err == nil {
+					assert.Check(t, strings.HasSuffix(r.Get("URL"), ":"+port))
+				}
+				_ = env.Graph()
+			}
+		})
+		wg.Go(func() {
+			// Readers of a shared snapshot
+			for range 50 {
+				for name, v := range resolved.All() {
+					assert.Check(t, resolved.Get(name) == v.Value)
+				}
+				_ = resolved.Environ()
+				_, _, _ = resolved.Provenance("URL")
+			}
+		})
+	}
+	wg.Go(func() {
+		// Edits return copies and leave the shared EnvFile unchanged
+		edited := env
+		for i := range 50 {
+			var err error
+			edited, err = edited.Set(fmt.Sprintf("EXTRA_%d", i), "value")
+			assert.Check(t, err)
+			var out strings.Builder
+			_, err = edited.WriteTo(&out)
+			assert.Check(t, err)
+		}
+		assert.Check(t, len(edited.Variables) == len(env.Variables)+50)
+	})
+	wg.Wait()
+
+	assert.Equal(t, resolved.Get("URL"), "http://localhost:80")
+}
@@ -0,0 +1,394 @@
+package dotenv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema declares the variables expected in .env files, to validate resolved variables
+type Schema struct {
+	// Variables are the declarations of the schema by variable name
+	Variables map[string]VariableSchema `json:"variables"`
+}
+
+// VariableSchema declares the expected value of a variable
+type VariableSchema struct {
+	// Description documents the variable
+	Description string `json:"description,omitempty"`
+	// Type is the type of the value: string (the default), int, int64, uint, float, float64, bool, url, port,
+	// duration, time (RFC 3339), email, []T for a list of T items, or map[K]V for a list of key:value entries.
+	// Integers are decimal
+	Type string `json:"type,omitempty"`
+	// Separator separates the items of lists and the entries of maps, a comma by default
+	Separator string `json:"separator,omitempty"`
+	// Required variables must be set to a non-empty value
+	Required bool `json:"required,omitempty"`
+	// Default is the value of the variable when it isn't set, emitted as the default of env tags by dotenv generate
+	Default *string `json:"default,omitempty"`
+	// Pattern is a regular expression the whole value, or each item of a list, must match
+	Pattern string `json:"pattern,omitempty"`
+	// Enum lists the allowed values, or the allowed items of a list
+	Enum []string `json:"enum,omitempty"`
+	// Deprecated is set on deprecated variables, with a message like "use DATABASE_URL instead"
+	Deprecated *string `json:"deprecated,omitempty"`
+	// Location is where the variable is declared in a template, empty for JSON schemas
+	Location Location `json:"-"`
+}
+
+// ReadSchema reads a JSON schema, like:
+//
+//	{"variables": {"PORT": {"type": "port", "required": true}, "LOG_LEVEL": {"enum": ["debug", "info"]}}}
+func ReadSchema(r io.Reader) (*Schema, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	var schema Schema
+	if err := decoder.Decode(&schema); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if schema.Variables == nil {
+		schema.Variables = make(map[string]VariableSchema)
+	}
+	for _, name := range slices.Sorted(maps.Keys(schema.Variables)) {
+		if err := schema.Variables[name].check(); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return &schema, nil
+}
+
+// TemplateSchema returns the schema declared by a template .env file, like an .env.example.
+// Every variable of the template is declared, with the annotations of the comment lines right above it:
+//
+//	# Port the server listens on
+//	# @type port @required @default 8080
+//	PORT=
+//
+// Comment text before the annotations is the description of the variable. The annotations are:
+//   - @type T: type of the value, see VariableSchema.Type
+//   - @required: the variable must be set to a non-empty value
+//   - @default value: default value
+//   - @sep separator: separator of list items and map entries
+//   - @pattern regexp: regular expression the value must match
+//   - @enum a|b|c: allowed values
+//   - @deprecated [message]: the variable is deprecated
+//
+// The value of an annotation extends to the next annotation. The template must have a syntax tree to read
+// comments from, as parsed files do
+func TemplateSchema(env *EnvFile) (*Schema, error) {
+	if len(env.Nodes) == 0 && len(env.Variables) > 0 {
+		return nil, errors.New("template has no syntax tree to read annotations from")
+	}
+	schema := &Schema{Variables: make(map[string]VariableSchema)}
+	var comments []string
+	k := 0
+	for _, node := range env.Nodes {
+		switch node.Kind {
+		case CommentNode:
+			comments = append(comments, strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(node.Text), "#")))
+			continue
+		case VariableNode:
+			if k >= len(env.Variables) {
+				return nil, errors.New("syntax tree is out of sync with variables")
+			}
+			variable := env.Variables[k]
+			k++
+			if _, ok := schema.Variables[variable.Name]; !ok {
+				declaration, err := parseAnnotations(comments)
+				if err == nil {
+					err = declaration.check()
+				}
+				if err != nil {
+					return nil, fmt.Errorf("%s: %s: %w", variable.Location, variable.Name, err)
+				}
+				declaration.Location = variable.Location
+				schema.Variables[variable.Name] = declaration
+			}
+		}
+		comments = nil
+	}
+	return schema, nil
+}
+
+// parseAnnotations parses the comment lines above a variable of a template
+func parseAnnotations(comments []string) (VariableSchema, error) {
+	var declaration VariableSchema
+	var description []string
+	for _, comment := range comments {
+		text, annotations := comment, ""
+		if idx := strings.Index(comment, "@"); idx != -1 && (idx == 0 || comment[idx-1] == ' ') {
+			text, annotations = strings.TrimSpace(comment[:idx]), comment[idx:]
+		}
+		if text != "" {
+			description = append(description, text)
+		}
+
+		for _, annotation := range splitAnnotations(annotations) {
+			name, value, _ := strings.Cut(annotation, " ")
+			value = strings.TrimSpace(value)
+			switch name {
+			case "@required":
+				if value != "" {
+					return declaration, fmt.Errorf("annotation %s takes no value", name)
+				}
+				declaration.Required = true
+			case "@deprecated":
+				declaration.Deprecated = &value
+			case "@type", "@default", "@sep", "@pattern", "@enum":
+				if value == "" {
+					return declaration, fmt.Errorf("annotation %s requires a value", name)
+				}
+				switch name {
+				case "@type":
+					declaration.Type = value
+				case "@default":
+					declaration.Default = &value
+				case "@sep":
+					declaration.Separator = value
+				case "@pattern":
+					declaration.Pattern = value
+				case "@enum":
+					declaration.Enum = strings.Split(value, "|")
+				}
+			default:
+				return declaration, fmt.Errorf("unknown annotation %s", name)
+			}
+		}
+	}
+	declaration.Description = strings.Join(description, "\n")
+	return declaration, nil
+}
+
+// splitAnnotations splits text into annotations, each starting with a word prefixed by @
+func splitAnnotations(text string) []string {
+	var annotations []string
+	for _, word := range strings.Fields(text) {
+		if strings.HasPrefix(word, "@") || len(annotations) == 0 {
+			annotations = append(annotations, word)
+			continue
+		}
+		annotations[len(annotations)-1] += " " + word
+	}
+	return annotations
+}
+
+// check reports an invalid type or pattern
+func (s VariableSchema) check() error {
+	if err := checkType(s.typ()); err != nil {
+		return err
+	}
+	if s.Pattern != "" {
+		if _, err := regexp.Compile(s.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+	return nil
+}
+
+// typ returns the type of the value, string by default
+func (s VariableSchema) typ() string {
+	if s.Type == "" {
+		return "string"
+	}
+	return s.Type
+}
+
+// scalarTypes are the types of single values
+var scalarTypes = []string{"string", "int", "int64", "uint", "float", "float64", "bool", "url", "port", "duration", "time", "email"}
+
+func checkType(typ string) error {
+	if elem, ok := strings.CutPrefix(typ, "[]"); ok {
+		return checkType(elem)
+	}
+	if key, elem, ok := cutMapType(typ); ok {
+		if err := checkType(key); err != nil {
+			return err
+		}
+		return checkType(elem)
+	}
+	if !slices.Contains(scalarTypes, typ) {
+		return fmt.Errorf("unknown type %q", typ)
+	}
+	return nil
+}
+
+// cutMapType returns the key and value types of a map[K]V type
+func cutMapType(typ string) (string, string, bool) {
+	kv, ok := strings.CutPrefix(typ, "map[")
+	if !ok {
+		return "", "", false
+	}
+	return strings.Cut(kv, "]")
+}
+
+// ValidationError reports a resolved variable that doesn't match its declaration in a Schema
+type ValidationError struct {
+	Variable string
+	// Location is where the variable is defined, or where it is declared when a required variable isn't set
+	Location Location
+	Message  string
+	// Deprecated is true when the variable is valid but deprecated, callers may report it as a warning
+	Deprecated bool
+}
+
+func (e *ValidationError) Error() string {
+	if e.Location != "" {
+		return fmt.Sprintf("%s: %s: %s", e.Location, e.Variable, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Variable, e.Message)
+}
+
+// Validate checks the resolved variables against the schema and returns all violations as *ValidationError,
+// joined by errors.Join: values that don't match their type, pattern or allowed values, deprecated variables,
+// then required variables which aren't set or are empty.
+// Variables the schema doesn't declare are ignored
+func (s *Schema) Validate(resolved *ResolvedEnv) error {
+	var errs []error
+	for name, v := range resolved.All() {
+		declaration, ok := s.Variables[name]
+		if !ok {
+			continue
+		}
+		if declaration.Deprecated != nil {
+			message := "deprecated"
+			if *declaration.Deprecated != "" {
+				message += ": " + *declaration.Deprecated
+			}
+			errs = append(errs, &ValidationError{Variable: name, Location: v.Location, Message: message, Deprecated: true})
+		}
+		if v.Value == "" {
+			continue
+		}
+		if err := declaration.validate(v.Value); err != nil {
+			errs = append(errs, &ValidationError{Variable: name, Location: v.Location, Message: err.Error()})
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(s.Variables)) {
+		declaration := s.Variables[name]
+		// A required variable with a default value is set by Unmarshal when missing
+		if !declaration.Required || declaration.Default != nil {
+			continue
+		}
+		v, ok := resolved.Lookup(name)
+		if ok && v.Value != "" {
+			continue
+		}
+		location := v.Location
+		if !ok {
+			location = declaration.Location
+		}
+		errs = append(errs, &ValidationError{Variable: name, Location: location, Message: "required variable is not set"})
+	}
+	return errors.Join(errs...)
+}
+
+// validate checks a non-empty value against the declaration
+func (s VariableSchema) validate(value string) error {
+	sep := s.Separator
+	if sep == "" {
+		sep = ","
+	}
+	items, err := validateType(s.typ(), value, sep)
+	if err != nil {
+		return err
+	}
+	var pattern *regexp.Regexp
+	if s.Pattern != "" {
+		if pattern, err = regexp.Compile("^(?:" + s.Pattern + ")$"); err != nil {
+			return err
+		}
+	}
+	for _, item := range items {
+		if pattern != nil {
+			if !pattern.MatchString(item) {
+				return fmt.Errorf("value %q doesn't match pattern %s", item, s.Pattern)
+			}
+		}
+		if len(s.Enum) > 0 && !slices.Contains(s.Enum, item) {
+			return fmt.Errorf("value %q isn't one of %s", item, strings.Join(s.Enum, ", "))
+		}
+	}
+	return nil
+}
+
+// validateType checks value is a valid typ, and returns the scalar items the value holds: the value itself,
+// the items of a list or the values of map entries
+func validateType(typ, value, sep string) ([]string, error) {
+	if elem, ok := strings.CutPrefix(typ, "[]"); ok {
+		var items []string
+		for _, item := range strings.Split(value, sep) {
+			values, err := validateType(elem, strings.TrimSpace(item), sep)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, values...)
+		}
+		return items, nil
+	}
+	if keyType, elemType, ok := cutMapType(typ); ok {
+		var items []string
+		for _, entry := range strings.Split(value, sep) {
+			k, v, ok := strings.Cut(entry, ":")
+			if !ok {
+				return nil, fmt.Errorf("invalid map entry %q, key:value expected", entry)
+			}
+			if _, err := validateType(keyType, strings.TrimSpace(k), sep); err != nil {
+				return nil, err
+			}
+			values, err := validateType(elemType, strings.TrimSpace(v), sep)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, values...)
+		}
+		return items, nil
+	}
+
+	var err error
+	switch typ {
+	case "int", "int64":
+		_, err = strconv.ParseInt(value, 10, 64)
+	case "uint":
+		_, err = strconv.ParseUint(value, 10, 64)
+	case "float", "float64":
+		_, err = strconv.ParseFloat(value, 64)
+	case "bool":
+		_, err = strconv.ParseBool(value)
+	case "port":
+		var port uint64
+		port, err = strconv.ParseUint(value, 10, 16)
+		if err == nil && port == 0 {
+			err = errors.New("port 0")
+		}
+	case "duration":
+		_, err = time.ParseDuration(value)
+	case "time":
+		_, err = time.Parse(time.RFC3339, value)
+	case "url":
+		var u *url.URL
+		u, err = url.Parse(value)
+		if err == nil && u.Scheme == "" {
+			err = errors.New("missing scheme")
+		}
+	case "email":
+		var address *mail.Address
+		address, err = mail.ParseAddress(value)
+		if err == nil && address.Address != value {
+			err = errors.New("not a bare address")
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s value %q", typ, value)
+	}
+	return []string{value}, nil
+}
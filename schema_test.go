@@ -0,0 +1,155 @@
+package dotenv_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/compose-spec/dotenv"
+	"gotest.tools/v3/assert"
+)
+
+func TestSchemaValidate(t *testing.T) {
+	template := strings.Join([]string{
+		"# Port the server listens on",
+		"# @type port @required",
+		"PORT=",
+		"# @type bool",
+		"DEBUG=",
+		"# @type url",
+		"DATABASE_URL=",
+		"# @type email",
+		"ADMIN_EMAIL=",
+		"# @type duration",
+		"TIMEOUT=",
+		"# @enum debug|info|warn",
+		"LOG_LEVEL=",
+		"# @type []port @sep ;",
+		"PORTS=",
+		"# @pattern [a-z]+(-[a-z]+)*",
+		"SLUG=",
+		"# @deprecated use DATABASE_URL",
+		"DB_HOST=",
+		"# @required",
+		"SECRET=",
+		"NAME=",
+		"# @type int",
+		"WORKERS=",
+		"# @type int64",
+		"MAX_SIZE=",
+		"# @type float64",
+		"RATIO=",
+		"# @type port @required @default 8080",
+		"HTTP_PORT=",
+	}, "\n")
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader(template), dotenv.WithSourceName(".env.example"))
+	assert.NilError(t, err)
+	schema, err := dotenv.TemplateSchema(env)
+	assert.NilError(t, err)
+	assert.Equal(t, schema.Variables["PORT"].Description, "Port the server listens on")
+	assert.Equal(t, schema.Variables["PORT"].Location, dotenv.Location(".env.example:3"))
+
+	tests := []struct {
+		name   string
+		input  string
+		errors []string
+	}{
+		{
+			name:  "valid",
+			input: "PORT=8080\nDEBUG=true\nDATABASE_URL=postgres://db/app\nADMIN_EMAIL=admin@example.com\nTIMEOUT=5s\nLOG_LEVEL=info\nPORTS=80; 443\nSLUG=my-app\nSECRET=s3cr3t\nNAME=anything\nWORKERS=08\nMAX_SIZE=10485760\nRATIO=0.5",
+		},
+		{
+			name:  "invalid",
+			input: "PORT=80800\nDEBUG=yes\nDATABASE_URL=/app\nADMIN_EMAIL=Admin <admin@example.com>\nTIMEOUT=5\nLOG_LEVEL=trace\nPORTS=80;http\nSLUG=My App\nDB_HOST=db\nWORKERS=0x10\nMAX_SIZE=1.5\nRATIO=half",
+			errors: []string{
+				`app.env:1: PORT: invalid port value "80800"`,
+				`app.env:2: DEBUG: invalid bool value "yes"`,
+				`app.env:3: DATABASE_URL: invalid url value "/app"`,
+				`app.env:4: ADMIN_EMAIL: invalid email value "Admin <admin@example.com>"`,
+				`app.env:5: TIMEOUT: invalid duration value "5"`,
+				`app.env:6: LOG_LEVEL: value "trace" isn't one of debug, info, warn`,
+				`app.env:7: PORTS: invalid port value "http"`,
+				`app.env:8: SLUG: value "My App" doesn't match pattern [a-z]+(-[a-z]+)*`,
+				`app.env:9: DB_HOST: deprecated: use DATABASE_URL`,
+				`app.env:10: WORKERS: invalid int value "0x10"`,
+				`app.env:11: MAX_SIZE: invalid int64 value "1.5"`,
+				`app.env:12: RATIO: invalid float64 value "half"`,
+				`.env.example:21: SECRET: required variable is not set`,
+			},
+		},
+		{
+			name:  "empty required",
+			input: "PORT=\nSECRET=${UNSET}\nDEBUG=",
+			errors: []string{
+				`app.env:1: PORT: required variable is not set`,
+				`app.env:2: SECRET: required variable is not set`,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env, err := dotenv.Parse(context.TODO(), strings.NewReader(test.input), dotenv.WithSourceName("app.env"))
+			assert.NilError(t, err)
+			resolved, err := env.ResolveEnv(context.TODO(), nil)
+			assert.NilError(t, err)
+
+			err = schema.Validate(resolved)
+			if len(test.errors) == 0 {
+				assert.NilError(t, err)
+				return
+			}
+			assert.Error(t, err, strings.Join(test.errors, "\n"))
+		})
+	}
+}
+
+func TestReadSchema(t *testing.T) {
+	schema, err := dotenv.ReadSchema(strings.NewReader(`{
+		"variables": {
+			"PORT": {"type": "port", "required": true},
+			"HOSTS": {"type": "[]url", "separator": " "},
+			"OLD_PORT": {"deprecated": ""}
+		}
+	}`))
+	assert.NilError(t, err)
+
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader("PORT=0\nHOSTS=http://a ftp\nOLD_PORT=80"))
+	assert.NilError(t, err)
+	resolved, err := env.ResolveEnv(context.TODO(), nil)
+	assert.NilError(t, err)
+	err = schema.Validate(resolved)
+	assert.Error(t, err, `:1: PORT: invalid port value "0"`+"\n"+`:2: HOSTS: invalid url value "ftp"`+"\n"+`:3: OLD_PORT: deprecated`)
+
+	var validationErr *dotenv.ValidationError
+	assert.Assert(t, errors.As(err, &validationErr))
+	assert.Equal(t, validationErr.Variable, "PORT")
+	assert.Assert(t, !validationErr.Deprecated)
+}
+
+func TestSchemaErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		err      string
+	}{
+		{name: "unknown type", template: "# @type integer\nPORT=", err: `.env.example:2: PORT: unknown type "integer"`},
+		{name: "invalid pattern", template: "# @pattern [a-z\nNAME=", err: ".env.example:2: NAME: invalid pattern: error parsing regexp: missing closing ]: `[a-z`"},
+		{name: "missing value", template: "# @enum\nLEVEL=", err: ".env.example:2: LEVEL: annotation @enum requires a value"},
+		{name: "unknown annotation", template: "# @secret\nTOKEN=", err: ".env.example:2: TOKEN: unknown annotation @secret"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env, err := dotenv.Parse(context.TODO(), strings.NewReader(test.template), dotenv.WithSourceName(".env.example"))
+			assert.NilError(t, err)
+			_, err = dotenv.TemplateSchema(env)
+			assert.Error(t, err, test.err)
+		})
+	}
+
+	_, err := dotenv.TemplateSchema(&dotenv.EnvFile{Variables: []dotenv.Variable{{Name: "PORT"}}})
+	assert.Error(t, err, "template has no syntax tree to read annotations from")
+
+	_, err = dotenv.ReadSchema(strings.NewReader(`{"variables": {"PORT": {"kind": "port"}}}`))
+	assert.Error(t, err, `invalid schema: json: unknown field "kind"`)
+}
@@ -0,0 +1,112 @@
+package dotenv
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// NodeKind identifies the kind of a syntax tree node
+type NodeKind int
+
+const (
+	// BlankNode is an empty line
+	BlankNode NodeKind = iota
+	// CommentNode is a line starting with #
+	CommentNode
+	// VariableNode is a variable assignment, possibly spanning multiple lines
+	VariableNode
+	// ExportNode is an "export VARIABLE" line exporting a variable defined earlier
+	ExportNode
+	// InvalidNode holds text that failed to parse, only produced by WithErrorRecovery
+	InvalidNode
+)
+
+// Node is an entry of the lossless syntax tree of an .env file.
+// Printing all the nodes of an EnvFile in order reproduces the parsed input byte for byte
+type Node struct {
+	Kind NodeKind
+	// Text is the raw content of blank, comment, export and invalid nodes, without line terminator
+	Text string
+
+	// Export is the "export " prefix of a variable assignment, if any
+	Export string
+	// Indent is the whitespace before the variable name
+	Indent string
+	// Name is the variable name of a VariableNode or ExportNode
+	Name string
+	// SpaceBefore and SpaceAfter are the whitespace around the = or : Separator
+	SpaceBefore string
+	Separator   string
+	SpaceAfter  string
+	// Value is the value as written, including quotes, escape sequences and line breaks of multi-line values
+	Value string
+	// Trailing is the whitespace and inline comment following the value
+	Trailing string
+
+	// EOL is the line terminator ending the node: "\n", "\r\n", or empty at end of input
+	EOL string
+}
+
+// String returns the source text of the node, including its line terminator
+func (n Node) String() string {
+	if n.Kind != VariableNode {
+		return n.Text + n.EOL
+	}
+	return n.Export + n.Indent + n.Name + n.SpaceBefore + n.Separator + n.SpaceAfter + n.Value + n.Trailing + n.EOL
+}
+
+// WriteTo writes the syntax tree of the EnvFile to w.
+// An EnvFile that hasn't been modified since it was parsed is written back exactly as it was read.
+// An EnvFile without syntax tree is written one variable per line, using the quote style of each variable when it
+// can represent the variable's raw value, and double quotes otherwise
+func (e *EnvFile) WriteTo(w io.Writer) (int64, error) {
+	nodes := e.Nodes
+	if len(nodes) == 0 {
+		entries, err := e.entries()
+		if err != nil {
+			return 0, err
+		}
+		for _, en := range entries {
+			nodes = append(nodes, en.node)
+		}
+	}
+
+	var n int64
+	for _, node := range nodes {
+		written, err := io.WriteString(w, node.String())
+		n += int64(written)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// lineReader reads lines from an input while keeping track of their terminators,
+// so that the syntax tree can reproduce the input exactly
+type lineReader struct {
+	reader *bufio.Reader
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{reader: bufio.NewReader(r)}
+}
+
+// next returns the next line without its terminator, which is returned separately.
+// Like bufio.ScanLines, a carriage return preceding the end of line is considered part of the terminator
+func (l *lineReader) next() (string, string, error) {
+	line, err := l.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", "", err
+	}
+
+	var eol string
+	if strings.HasSuffix(line, "\n") {
+		line, eol = line[:len(line)-1], "\n"
+	}
+	if strings.HasSuffix(line, "\r") {
+		line, eol = line[:len(line)-1], "\r"+eol
+	}
+	return line, eol, nil
+}
@@ -0,0 +1,100 @@
+package dotenv_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/compose-spec/dotenv"
+	"gotest.tools/v3/assert"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "empty", input: ""},
+		{name: "simple", input: "FOO=bar\n"},
+		{name: "no final newline", input: "FOO=bar"},
+		{name: "blank lines and comments", input: "# header\n\n\nFOO=bar\n# trailer\n\n"},
+		{name: "export prefix", input: "export FOO=bar\nexport  BAR = baz\n"},
+		{name: "export without assignment", input: "FOO=bar\nexport FOO\n"},
+		{name: "yaml style separator", input: "FOO: bar\nBAR :baz\n"},
+		{name: "spacing", input: "  FOO  =  bar  \n\tBAR\t=\tbaz\t\n"},
+		{name: "inline comment", input: "FOO=bar   # a comment  \n"},
+		{name: "quoted values", input: "FOO=\"bar # baz\"  \nBAR='qux'\nBAZ=\"a \\\"b\\\" \\n\"\n"},
+		{name: "empty values", input: "FOO=\nBAR=   \nBAZ=\"\"\n"},
+		{name: "multi-line value", input: "FOO=\"line1  \n  line2\n line3\"\nBAR='a\n\nb'\n"},
+		{name: "crlf line endings", input: "# comment\r\nFOO=bar\r\n\r\nBAR=\"multi\r\nline\"\r\n"},
+		{name: "carriage return at end of input", input: "FOO=bar\r"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env, err := dotenv.Parse(context.TODO(), strings.NewReader(test.input))
+			assert.NilError(t, err)
+
+			var out strings.Builder
+			n, err := env.WriteTo(&out)
+			assert.NilError(t, err)
+			assert.Equal(t, out.String(), test.input)
+			assert.Equal(t, int(n), len(test.input))
+		})
+	}
+}
+
+func TestRoundTripWithErrorRecovery(t *testing.T) {
+	input := "FOO=bar\nINVALID LINE\n1FOO=bar\r\nexport UNSET\nBAR=\"unterminated\n\nvalue\n"
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader(input), dotenv.WithErrorRecovery())
+	assert.ErrorContains(t, err, "unterminated")
+
+	var out strings.Builder
+	_, err = env.WriteTo(&out)
+	assert.NilError(t, err)
+	assert.Equal(t, out.String(), input)
+
+	var kinds []dotenv.NodeKind
+	for _, node := range env.Nodes {
+		kinds = append(kinds, node.Kind)
+	}
+	assert.DeepEqual(t, []dotenv.NodeKind{
+		dotenv.VariableNode,
+		dotenv.InvalidNode,
+		dotenv.InvalidNode,
+		dotenv.InvalidNode,
+		dotenv.InvalidNode,
+	}, kinds)
+}
+
+func TestSyntaxTree(t *testing.T) {
+	input := "# database\nexport  DB_HOST : \"db\"  \n\nDB_PORT=5432 # default port\nexport DB_PORT\n"
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader(input))
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, []dotenv.Node{
+		{Kind: dotenv.CommentNode, Text: "# database", EOL: "\n"},
+		{
+			Kind:        dotenv.VariableNode,
+			Export:      "export ",
+			Indent:      " ",
+			Name:        "DB_HOST",
+			SpaceBefore: " ",
+			Separator:   ":",
+			SpaceAfter:  " ",
+			Value:       `"db"`,
+			Trailing:    "  ",
+			EOL:         "\n",
+		},
+		{Kind: dotenv.BlankNode, EOL: "\n"},
+		{
+			Kind:      dotenv.VariableNode,
+			Name:      "DB_PORT",
+			Separator: "=",
+			Value:     "5432",
+			Trailing:  " # default port",
+			EOL:       "\n",
+		},
+		{Kind: dotenv.ExportNode, Text: "export DB_PORT", Name: "DB_PORT", EOL: "\n"},
+	}, env.Nodes)
+}
@@ -0,0 +1,70 @@
+package dotenv
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+// CheckTemplate compares an .env file with its template, like an .env.example, and returns all differences
+// as *TemplateError joined by errors.Join: variables of the template missing from the file, in template order,
+// then variables of the file the template doesn't declare and variables still set to a placeholder value
+// of the template, in file order.
+// Placeholders are empty values and clear markers like <database password>, changeme, TODO, xxx or your-api-key,
+// but not plausible values like password. Raw values are compared, nothing is resolved
+func CheckTemplate(template, actual *EnvFile) error {
+	declared := make(map[string]Variable)
+	for _, v := range template.Variables {
+		if _, ok := declared[v.Name]; !ok {
+			declared[v.Name] = v
+		}
+	}
+	// Later definitions override earlier ones
+	defined := make(map[string]Variable)
+	for _, v := range actual.Variables {
+		defined[v.Name] = v
+	}
+
+	var errs []error
+	reported := make(map[string]bool)
+	for _, v := range template.Variables {
+		if _, ok := defined[v.Name]; !ok && !reported[v.Name] {
+			reported[v.Name] = true
+			errs = append(errs, &TemplateError{Kind: MissingVariable, Variable: v.Name, Location: v.Location})
+		}
+	}
+	for _, v := range actual.Variables {
+		if reported[v.Name] {
+			continue
+		}
+		tv, ok := declared[v.Name]
+		switch {
+		case !ok:
+			reported[v.Name] = true
+			errs = append(errs, &TemplateError{Kind: ExtraVariable, Variable: v.Name, Location: v.Location})
+		case defined[v.Name].Location == v.Location && v.RawValue == tv.RawValue && isPlaceholder(tv.RawValue):
+			reported[v.Name] = true
+			errs = append(errs, &TemplateError{Kind: UnfilledPlaceholder, Variable: v.Name, Location: v.Location, Placeholder: v.RawValue})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// placeholders are markers commonly used in templates for values to fill in, which can't be actual values
+var placeholders = []string{"changeme", "change-me", "change_me", "replaceme", "replace-me", "replace_me", "todo", "tbd", "fixme", "..."}
+
+// isPlaceholder returns whether a template value is a placeholder for a value to fill in
+func isPlaceholder(value string) bool {
+	lower := strings.ToLower(strings.TrimSpace(value))
+	switch {
+	case lower == "":
+		return true
+	case strings.HasPrefix(lower, "<") && strings.HasSuffix(lower, ">"):
+		return true
+	case strings.HasPrefix(lower, "your-") || strings.HasPrefix(lower, "your_"):
+		return true
+	case strings.Trim(lower, "x") == "":
+		return true
+	}
+	return slices.Contains(placeholders, lower)
+}
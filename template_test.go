@@ -0,0 +1,76 @@
+package dotenv_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/compose-spec/dotenv"
+	"gotest.tools/v3/assert"
+)
+
+func TestCheckTemplate(t *testing.T) {
+	template, err := dotenv.Parse(context.TODO(), strings.NewReader(strings.Join([]string{
+		"APP_NAME=api",
+		"DATABASE_URL=<database url>",
+		"API_KEY=your-api-key",
+		"SECRET=changeme",
+		"TOKEN=xxxx",
+		"LOG_LEVEL=info",
+		"PORT=",
+		"DB_PASSWORD=password",
+		"CACHE_SECRET=secret",
+	}, "\n")), dotenv.WithSourceName(".env.example"))
+	assert.NilError(t, err)
+
+	tests := []struct {
+		name   string
+		actual string
+		errors []string
+	}{
+		{
+			name:   "complete",
+			actual: "APP_NAME=api\nDATABASE_URL=postgres://db/app\nAPI_KEY=k3y\nSECRET=s3cr3t\nTOKEN=t0k3n\nLOG_LEVEL=info\nPORT=80\nDB_PASSWORD=password\nCACHE_SECRET=secret",
+		},
+		{
+			name:   "missing and extra",
+			actual: "APP_NAME=api\nDATABASE_URL=postgres://db/app\nAPI_KEY=k3y\nSECRET=s3cr3t\nDEBUG=true\nPORT=80\nDEBUG=false\nDB_PASSWORD=dev\nCACHE_SECRET=dev",
+			errors: []string{
+				".env.example:5: TOKEN: declared by the template but not defined",
+				".env.example:6: LOG_LEVEL: declared by the template but not defined",
+				".env:5: DEBUG: not declared by the template",
+			},
+		},
+		{
+			name:   "placeholders",
+			actual: "APP_NAME=api\nDATABASE_URL=<database url>\nAPI_KEY=your-api-key\nSECRET=changeme\nSECRET=CHANGEME\nTOKEN=xxxx\nLOG_LEVEL=info\nPORT=\nDB_PASSWORD=password\nCACHE_SECRET=secret",
+			errors: []string{
+				`.env:2: DATABASE_URL: placeholder value "<database url>" of the template was not replaced`,
+				`.env:3: API_KEY: placeholder value "your-api-key" of the template was not replaced`,
+				`.env:6: TOKEN: placeholder value "xxxx" of the template was not replaced`,
+				`.env:8: PORT: empty value of the template was not filled in`,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := dotenv.Parse(context.TODO(), strings.NewReader(test.actual), dotenv.WithSourceName(".env"))
+			assert.NilError(t, err)
+
+			err = dotenv.CheckTemplate(template, actual)
+			if len(test.errors) == 0 {
+				assert.NilError(t, err)
+				return
+			}
+			assert.Error(t, err, strings.Join(test.errors, "\n"))
+		})
+	}
+
+	actual, err := dotenv.Parse(context.TODO(), strings.NewReader("APP_NAME=api"))
+	assert.NilError(t, err)
+	var templateErr *dotenv.TemplateError
+	assert.Assert(t, errors.As(dotenv.CheckTemplate(template, actual), &templateErr))
+	assert.Equal(t, templateErr.Kind, dotenv.MissingVariable)
+	assert.Equal(t, templateErr.Variable, "DATABASE_URL")
+}
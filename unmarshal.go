@@ -0,0 +1,278 @@
+package dotenv
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Unmarshal decodes the resolved variables into the struct pointed to by v, following the env struct tags
+// of its fields:
+//
+//	Port    int           `env:"PORT,default=8080"`
+//	Hosts   []string      `env:"HOSTS,required,sep=;"`
+//	Timeout time.Duration `env:"TIMEOUT"`
+//	DB      Database      `env:",prefix=DB_"`
+//
+// The tag name is the variable name. Its options are:
+//   - required: the variable must be set to a non-empty value
+//   - default=value: value used when the variable isn't set or is empty, must be the last option
+//   - sep=separator: separator of slice items and map entries, a comma by default
+//   - layout=layout: layout of time.Time values, RFC 3339 by default
+//   - prefix=prefix: for a nested struct without name, prefix of the variable names of its fields
+//
+// Fields are converted from strings to bools, numbers, time.Duration, time.Time, url.URL, slices, maps
+// of key:value entries, encoding.TextUnmarshaler implementations, and pointers to those types.
+// Integers are decimal, a leading zero doesn't make them octal.
+// Fields without env tag are left untouched, except embedded structs which are decoded recursively,
+// as well as fields whose variable isn't set or is empty and have no default value.
+// All conversion errors are reported as *UnmarshalError, joined in the returned error
+func Unmarshal(resolved *ResolvedEnv, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cannot unmarshal into %T, a non-nil pointer to a struct is required", v)
+	}
+	return errors.Join(unmarshalStruct(resolved, rv.Elem(), "")...)
+}
+
+// UnmarshalError reports a variable that can't be decoded into a struct field
+type UnmarshalError struct {
+	// Variable is the name of the variable and Location where it is defined,
+	// empty when the variable isn't set or the struct tag is invalid
+	Variable string
+	Location Location
+	// Field is the name of the struct field, including the names of enclosing structs
+	Field string
+	Err   error
+}
+
+func (e *UnmarshalError) Error() string {
+	subject := e.Variable
+	if subject == "" {
+		subject = e.Field
+	}
+	msg := fmt.Sprintf("%s: %v", subject, e.Err)
+	if e.Location != "" {
+		return fmt.Sprintf("%s: %s", e.Location, msg)
+	}
+	return msg
+}
+
+func (e *UnmarshalError) Unwrap() error {
+	return e.Err
+}
+
+// envTag holds the options of an env struct tag
+type envTag struct {
+	name       string
+	required   bool
+	defaultSet bool
+	defaultVal string
+	sep        string
+	layout     string
+	prefix     string
+}
+
+func parseEnvTag(tag string) (envTag, error) {
+	name, options, _ := strings.Cut(tag, ",")
+	parsed := envTag{name: name, sep: ",", layout: time.RFC3339}
+	for options != "" {
+		var option string
+		if strings.HasPrefix(options, "default=") {
+			// The default value can contain commas
+			option, options = options, ""
+		} else {
+			option, options, _ = strings.Cut(options, ",")
+		}
+		key, value, _ := strings.Cut(option, "=")
+		switch key {
+		case "required":
+			parsed.required = true
+		case "default":
+			parsed.defaultSet, parsed.defaultVal = true, value
+		case "sep":
+			parsed.sep = value
+		case "layout":
+			parsed.layout = value
+		case "prefix":
+			parsed.prefix = value
+		default:
+			return parsed, fmt.Errorf("unknown env tag option %q", option)
+		}
+	}
+	return parsed, nil
+}
+
+// unmarshalStruct decodes the variables into the fields of the struct rv, returning all conversion errors
+func unmarshalStruct(resolved *ResolvedEnv, rv reflect.Value, prefix string) []error {
+	var errs []error
+	rt := rv.Type()
+	for i := range rt.NumField() {
+		field := rt.Field(i)
+		tag, tagged := field.Tag.Lookup("env")
+		if tag == "-" || !field.IsExported() {
+			continue
+		}
+		if !tagged {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				errs = append(errs, unmarshalStruct(resolved, rv.Field(i), prefix)...)
+			}
+			continue
+		}
+
+		options, err := parseEnvTag(tag)
+		if err != nil {
+			errs = append(errs, &UnmarshalError{Variable: prefix + options.name, Field: field.Name, Err: err})
+			continue
+		}
+		if options.name == "" {
+			if field.Type.Kind() != reflect.Struct {
+				errs = append(errs, &UnmarshalError{Field: field.Name, Err: errors.New("env tag requires a variable name")})
+				continue
+			}
+			for _, err := range unmarshalStruct(resolved, rv.Field(i), prefix+options.prefix) {
+				var unmarshalErr *UnmarshalError
+				if errors.As(err, &unmarshalErr) {
+					unmarshalErr.Field = field.Name + "." + unmarshalErr.Field
+				}
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		name := prefix + options.name
+		variable, ok := resolved.Lookup(name)
+		value := variable.Value
+		if !ok || value == "" {
+			switch {
+			case options.defaultSet:
+				value, variable.Location = options.defaultVal, ""
+			case options.required:
+				errs = append(errs, &UnmarshalError{Variable: name, Location: variable.Location, Field: field.Name, Err: errors.New("required variable is not set")})
+				continue
+			default:
+				continue
+			}
+		}
+		if err := decodeValue(rv.Field(i), value, options); err != nil {
+			errs = append(errs, &UnmarshalError{Variable: name, Location: variable.Location, Field: field.Name, Err: err})
+		}
+	}
+	return errs
+}
+
+var (
+	durationType        = reflect.TypeFor[time.Duration]()
+	timeType            = reflect.TypeFor[time.Time]()
+	urlType             = reflect.TypeFor[url.URL]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// decodeValue converts value to the type of rv and sets it
+func decodeValue(rv reflect.Value, value string, options envTag) error {
+	switch rv.Type() {
+	case durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		rv.SetInt(int64(d))
+		return nil
+	case timeType:
+		t, err := time.Parse(options.layout, value)
+		if err != nil {
+			return err
+		}
+		rv.Set(reflect.ValueOf(t))
+		return nil
+	case urlType:
+		u, err := url.Parse(value)
+		if err != nil {
+			return err
+		}
+		rv.Set(reflect.ValueOf(*u))
+		return nil
+	}
+	if reflect.PointerTo(rv.Type()).Implements(textUnmarshalerType) {
+		return rv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return invalidValue(value, rv.Type(), err)
+		}
+		rv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, rv.Type().Bits())
+		if err != nil {
+			return invalidValue(value, rv.Type(), err)
+		}
+		rv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, rv.Type().Bits())
+		if err != nil {
+			return invalidValue(value, rv.Type(), err)
+		}
+		rv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, rv.Type().Bits())
+		if err != nil {
+			return invalidValue(value, rv.Type(), err)
+		}
+		rv.SetFloat(f)
+	case reflect.Pointer:
+		elem := reflect.New(rv.Type().Elem())
+		if err := decodeValue(elem.Elem(), value, options); err != nil {
+			return err
+		}
+		rv.Set(elem)
+	case reflect.Slice:
+		items := strings.Split(value, options.sep)
+		slice := reflect.MakeSlice(rv.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decodeValue(slice.Index(i), strings.TrimSpace(item), options); err != nil {
+				return err
+			}
+		}
+		rv.Set(slice)
+	case reflect.Map:
+		m := reflect.MakeMap(rv.Type())
+		for _, entry := range strings.Split(value, options.sep) {
+			k, v, ok := strings.Cut(entry, ":")
+			if !ok {
+				return fmt.Errorf("invalid map entry %q, key:value expected", entry)
+			}
+			key := reflect.New(rv.Type().Key()).Elem()
+			if err := decodeValue(key, strings.TrimSpace(k), options); err != nil {
+				return err
+			}
+			elem := reflect.New(rv.Type().Elem()).Elem()
+			if err := decodeValue(elem, strings.TrimSpace(v), options); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, elem)
+		}
+		rv.Set(m)
+	default:
+		return fmt.Errorf("unsupported type %s", rv.Type())
+	}
+	return nil
+}
+
+// invalidValue reports a value which can't be parsed as typ, without the details of strconv errors
+func invalidValue(value string, typ reflect.Type, err error) error {
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) {
+		err = numErr.Err
+	}
+	return fmt.Errorf("invalid %s value %q: %w", typ, value, err)
+}
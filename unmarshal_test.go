@@ -0,0 +1,156 @@
+package dotenv_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/compose-spec/dotenv"
+	"gotest.tools/v3/assert"
+)
+
+type level int
+
+func (l *level) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "debug":
+		*l = 0
+	case "info":
+		*l = 1
+	default:
+		return errors.New("unknown level " + string(text))
+	}
+	return nil
+}
+
+type database struct {
+	Host string `env:"HOST,default=localhost"`
+	Port uint16 `env:"PORT,default=5432"`
+}
+
+type Common struct {
+	Name string `env:"APP_NAME"`
+}
+
+type config struct {
+	Common
+	Port     int               `env:"PORT,default=8080"`
+	Debug    bool              `env:"DEBUG"`
+	Ratio    float64           `env:"RATIO"`
+	Timeout  time.Duration     `env:"TIMEOUT,required"`
+	Started  time.Time         `env:"STARTED"`
+	Day      time.Time         `env:"DAY,layout=2006-01-02"`
+	Hosts    []string          `env:"HOSTS,default=a,b"`
+	Ports    []int             `env:"PORTS,sep=;"`
+	Labels   map[string]string `env:"LABELS"`
+	Endpoint url.URL           `env:"ENDPOINT"`
+	Proxy    *url.URL          `env:"PROXY"`
+	Level    level             `env:"LEVEL"`
+	Limit    *int              `env:"LIMIT"`
+	Primary  database          `env:",prefix=DB_"`
+	Replica  database          `env:",prefix=REPLICA_"`
+	Ignored  string            `env:"-"`
+	Untagged string
+}
+
+func TestUnmarshal(t *testing.T) {
+	input := strings.Join([]string{
+		"APP_NAME=api",
+		"PORT=010",
+		"DEBUG=true",
+		"RATIO=0.5",
+		"TIMEOUT=1m30s",
+		"STARTED=2024-05-01T10:00:00Z",
+		"DAY=2024-05-01",
+		"PORTS=80; 443",
+		"LABELS=team:core,tier:backend",
+		"ENDPOINT=https://api.example.com/v1",
+		"PROXY=http://proxy:3128",
+		"LEVEL=info",
+		"LIMIT=${MAX:-10}",
+		"DB_HOST=db",
+		"REPLICA_PORT=06432",
+		"Ignored=value",
+		"Untagged=value",
+	}, "\n")
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader(input))
+	assert.NilError(t, err)
+	resolved, err := env.ResolveEnv(context.TODO(), nil)
+	assert.NilError(t, err)
+
+	var cfg config
+	assert.NilError(t, dotenv.Unmarshal(resolved, &cfg))
+
+	limit := 10
+	assert.DeepEqual(t, config{
+		Common:   Common{Name: "api"},
+		Port:     10,
+		Debug:    true,
+		Ratio:    0.5,
+		Timeout:  90 * time.Second,
+		Started:  time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Day:      time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		Hosts:    []string{"a", "b"},
+		Ports:    []int{80, 443},
+		Labels:   map[string]string{"team": "core", "tier": "backend"},
+		Endpoint: url.URL{Scheme: "https", Host: "api.example.com", Path: "/v1"},
+		Proxy:    &url.URL{Scheme: "http", Host: "proxy:3128"},
+		Level:    1,
+		Limit:    &limit,
+		Primary:  database{Host: "db", Port: 5432},
+		Replica:  database{Host: "localhost", Port: 6432},
+	}, cfg)
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	input := strings.Join([]string{
+		"PORT=http",
+		"DEBUG=maybe",
+		"PORTS=80;x",
+		"LEVEL=trace",
+		"DB_PORT=70000",
+	}, "\n")
+	env, err := dotenv.Parse(context.TODO(), strings.NewReader(input), dotenv.WithSourceName("app.env"))
+	assert.NilError(t, err)
+	resolved, err := env.ResolveEnv(context.TODO(), nil)
+	assert.NilError(t, err)
+
+	var cfg config
+	err = dotenv.Unmarshal(resolved, &cfg)
+	assert.Error(t, err, strings.Join([]string{
+		`app.env:1: PORT: invalid int value "http": invalid syntax`,
+		`app.env:2: DEBUG: invalid bool value "maybe": invalid syntax`,
+		`TIMEOUT: required variable is not set`,
+		`app.env:3: PORTS: invalid int value "x": invalid syntax`,
+		`app.env:4: LEVEL: unknown level trace`,
+		`app.env:5: DB_PORT: invalid uint16 value "70000": value out of range`,
+	}, "\n"))
+
+	var unmarshalErr *dotenv.UnmarshalError
+	assert.Assert(t, errors.As(err, &unmarshalErr))
+	assert.Equal(t, unmarshalErr.Variable, "PORT")
+	assert.Equal(t, unmarshalErr.Location, dotenv.Location("app.env:1"))
+	assert.Equal(t, unmarshalErr.Field, "Port")
+	assert.Assert(t, errors.Is(err, strconv.ErrSyntax))
+
+	errs := err.(interface{ Unwrap() []error }).Unwrap()
+	assert.Assert(t, errors.As(errs[len(errs)-1], &unmarshalErr))
+	assert.Equal(t, unmarshalErr.Field, "Primary.Port")
+}
+
+func TestUnmarshalInvalidTarget(t *testing.T) {
+	resolved, err := dotenv.Load(context.TODO(), nil, nil)
+	assert.NilError(t, err)
+
+	var cfg config
+	assert.Error(t, dotenv.Unmarshal(resolved, cfg), "cannot unmarshal into dotenv_test.config, a non-nil pointer to a struct is required")
+
+	var invalid struct {
+		Field string `env:"FIELD,unknown"`
+	}
+	assert.Error(t, dotenv.Unmarshal(resolved, &invalid), `FIELD: unknown env tag option "unknown"`)
+}
@@ -1,5 +1,10 @@
 package dotenv
 
+import (
+	"errors"
+	"strings"
+)
+
 // Variable represents a single environment variable with its metadata
 type Variable struct {
 	Name     string
@@ -11,12 +16,62 @@ type Variable struct {
 }
 
 // expandValue replaces $VAR and ${VAR} references in the value
-func (v *Variable) expandValue(lookup LookupFn) error {
-	val, exp, err := expandString(v.RawValue, lookup)
+func (v *Variable) expandValue(x *expander, lookup ContextLookupFn) error {
+	if err := x.ctx.Err(); err != nil {
+		return err
+	}
+	undefined := len(x.undefined)
+	val, exp, err := x.expandString(v.RawValue, lookup)
 	if err != nil {
+		var expansionErr *ExpansionError
+		if errors.As(err, &expansionErr) {
+			v.locate(expansionErr, x.options.dialect)
+		}
 		return err
 	}
+	for _, undefinedErr := range x.undefined[undefined:] {
+		v.locate(undefinedErr, x.options.dialect)
+	}
 	v.Value = val
 	v.Expanded = exp
 	return nil
 }
+
+// locate sets the variable, location, column and snippet of an error raised while expanding the value
+func (v *Variable) locate(err *ExpansionError, dialect Dialect) {
+	err.Variable, err.Location = v.Name, v.Location
+	if err.Kind == CommandFailed {
+		// Commands are not references, find the $(command) substitution itself
+		if idx := strings.Index(v.RawValue, "$("+err.Reference+")"); idx != -1 {
+			err.Column, err.Snippet = idx+1, referenceText(v.RawValue, idx)
+		}
+		return
+	}
+	for _, ref := range findReferences(v.RawValue, dialect) {
+		if ref.name == err.Reference {
+			err.Column, err.Snippet = ref.offset+1, referenceText(v.RawValue, ref.offset)
+			return
+		}
+	}
+}
+
+// referenceText returns the text of the reference starting with the $ at offset in value
+func referenceText(value string, offset int) string {
+	end := -1
+	switch {
+	case strings.HasPrefix(value[offset:], "${"):
+		end = findClosingBrace(value, offset+2)
+	case strings.HasPrefix(value[offset:], "$("):
+		end = findClosingParen(value, offset+2)
+	default:
+		end = offset + 1
+		for end < len(value) && isVarNameChar(value[end]) {
+			end++
+		}
+		return value[offset:end]
+	}
+	if end == -1 {
+		return value[offset:]
+	}
+	return value[offset : end+1]
+}